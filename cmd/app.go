@@ -18,6 +18,7 @@ type RootCommandOptions struct {
 	logFormat string
 
 	ExpandCommandOptions
+	MirrorCommandOptions
 }
 
 func parseLogLevel(level string) (slog.Level, error) {
@@ -104,6 +105,7 @@ func NewRootCommand(options *RootCommandOptions) *cobra.Command {
 		"Log format (text or json)",
 	)
 	command.AddCommand(NewExpandCommand(&options.ExpandCommandOptions))
+	command.AddCommand(NewMirrorCommand(&options.MirrorCommandOptions))
 
 	return command
 }
@@ -3,19 +3,45 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path"
+	"time"
 
 	"github.com/fluxcd/pkg/git"
 	"github.com/fluxcd/pkg/git/gogit"
 	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
 	"helm.sh/helm/v3/pkg/chartutil"
 
 	"github.com/vladlosev/fouskoti/pkg/repository"
 )
 
 type ExpandCommandOptions struct {
-	credentialsFileName string
-	kubeVersion         string
-	apiVersions         []string
+	credentialsFileName    string
+	kubeVersion            string
+	apiVersions            []string
+	parallelism            int
+	cacheDir               string
+	cacheTTL               time.Duration
+	cacheMaxBytes          int64
+	noCache                bool
+	verify                 bool
+	keyring                string
+	verifySignatures       bool
+	cosignKeys             []string
+	cosignIdentity         string
+	cosignIdentityRegexp   string
+	cosignOIDCIssuer       string
+	cosignOIDCIssuerRegexp string
+	retryMaxAttempts       int
+	retryInitialBackoff    time.Duration
+	retryMaxBackoff        time.Duration
+	retryJitter            float64
+	rateLimit              float64
+	rateLimitBurst         int
+	hookPolicy             string
+	crdPolicy              string
+	postRenderer           string
+	renderMode             string
 }
 
 const ExpandCommandName = "expand"
@@ -75,7 +101,85 @@ func NewExpandCommand(options *ExpandCommandOptions) *cobra.Command {
 				) (repository.GitClientInterface, error) {
 					return gogit.NewClient(path, authOpts, clientOpts...)
 				},
+				nil,
+				nil,
+				options.parallelism,
 			)
+
+			if !options.noCache && options.cacheDir != "" {
+				expander.SetDiskCache(options.cacheDir, options.cacheTTL, options.cacheMaxBytes)
+				if err := expander.PruneChartCache(time.Now()); err != nil {
+					return fmt.Errorf("unable to prune chart cache: %w", err)
+				}
+			}
+
+			if options.keyring != "" || options.verify {
+				expander.SetProvenanceVerification(options.keyring, options.verify)
+			}
+
+			if options.verifySignatures {
+				expander.SetChartVerification(repository.ChartVerificationConfig{
+					Provider:             repository.ChartVerificationProviderCosign,
+					PublicKeys:           options.cosignKeys,
+					CertIdentity:         options.cosignIdentity,
+					CertIdentityRegexp:   options.cosignIdentityRegexp,
+					CertOIDCIssuer:       options.cosignOIDCIssuer,
+					CertOIDCIssuerRegexp: options.cosignOIDCIssuerRegexp,
+				})
+			}
+
+			if options.retryMaxAttempts > 0 {
+				expander.SetRetryPolicy(repository.RetryConfig{
+					MaxAttempts:    options.retryMaxAttempts,
+					InitialBackoff: options.retryInitialBackoff,
+					MaxBackoff:     options.retryMaxBackoff,
+					Jitter:         options.retryJitter,
+				})
+			}
+
+			if options.rateLimit > 0 {
+				expander.SetRateLimit(rate.Limit(options.rateLimit), options.rateLimitBurst)
+			}
+
+			switch repository.HookPolicy(options.hookPolicy) {
+			case repository.HookPolicyIncludeAll,
+				repository.HookPolicySkipTests,
+				repository.HookPolicySkipHooks,
+				repository.HookPolicyHooksOnly:
+				expander.SetHookPolicy(repository.HookPolicy(options.hookPolicy))
+			default:
+				return fmt.Errorf(
+					"invalid --hook-policy value %s (valid values are IncludeAll, SkipTests, SkipHooks, HooksOnly)",
+					options.hookPolicy,
+				)
+			}
+
+			switch repository.CRDPolicy(options.crdPolicy) {
+			case repository.CRDPolicySkip, repository.CRDPolicyInclude, repository.CRDPolicySeparate:
+				expander.SetCRDPolicy(repository.CRDPolicy(options.crdPolicy))
+			default:
+				return fmt.Errorf(
+					"invalid --crd-policy value %s (valid values are Skip, Include, Separate)",
+					options.crdPolicy,
+				)
+			}
+
+			if options.postRenderer != "" {
+				expander.SetPostRenderer(repository.NewExecPostRenderer(ctx, options.postRenderer))
+			}
+
+			switch repository.RenderMode(options.renderMode) {
+			case repository.RenderModeInstall,
+				repository.RenderModeUpgrade,
+				repository.RenderModeAuto:
+				expander.SetRenderMode(repository.RenderMode(options.renderMode))
+			default:
+				return fmt.Errorf(
+					"invalid --render-mode value %s (valid values are Install, Upgrade, Auto)",
+					options.renderMode,
+				)
+			}
+
 			return expander.ExpandHelmReleases(
 				credentials,
 				input,
@@ -108,6 +212,177 @@ func NewExpandCommand(options *ExpandCommandOptions) *cobra.Command {
 		[]string{},
 		"Kubernetes api versions used for Capabilities.APIVersions in charts",
 	)
+	command.PersistentFlags().IntVarP(
+		&options.parallelism,
+		"parallelism",
+		"",
+		4,
+		"Maximum number of HelmReleases to expand concurrently",
+	)
+	command.PersistentFlags().StringVarP(
+		&options.hookPolicy,
+		"hook-policy",
+		"",
+		string(repository.HookPolicyIncludeAll),
+		"Which Helm hook resources to include in the output "+
+			"(IncludeAll, SkipTests, SkipHooks, HooksOnly)",
+	)
+	command.PersistentFlags().StringVarP(
+		&options.crdPolicy,
+		"crd-policy",
+		"",
+		string(repository.CRDPolicySkip),
+		"Whether and where to emit a chart's CustomResourceDefinitions (Skip, Include, Separate)",
+	)
+	command.PersistentFlags().StringVarP(
+		&options.postRenderer,
+		"post-renderer",
+		"",
+		"",
+		"Path to an external post-renderer binary applied to every HelmRelease's rendered "+
+			"manifests, the way \"helm template --post-renderer\" does; disabled when empty",
+	)
+	command.PersistentFlags().StringVarP(
+		&options.renderMode,
+		"render-mode",
+		"",
+		string(repository.RenderModeInstall),
+		"Whether to render each HelmRelease as a first install or as an upgrade from a "+
+			"prior revision found in the input stream (Install, Upgrade, Auto)",
+	)
+	defaultCacheDir := ""
+	if userCacheDir, err := os.UserCacheDir(); err == nil {
+		defaultCacheDir = path.Join(userCacheDir, "fouskoti")
+	}
+	command.PersistentFlags().StringVarP(
+		&options.cacheDir,
+		"cache-dir",
+		"",
+		defaultCacheDir,
+		"Directory for the persistent on-disk chart cache; disabled when empty. "+
+			"Defaults to a fouskoti directory under the user cache directory "+
+			"(see os.UserCacheDir), when one can be determined",
+	)
+	command.PersistentFlags().DurationVarP(
+		&options.cacheTTL,
+		"cache-ttl",
+		"",
+		0,
+		"Maximum age of entries in the on-disk chart cache; 0 means entries never expire",
+	)
+	command.PersistentFlags().Int64VarP(
+		&options.cacheMaxBytes,
+		"cache-max-bytes",
+		"",
+		0,
+		"Maximum total size in bytes of the on-disk chart cache blobs; 0 means unbounded",
+	)
+	command.PersistentFlags().BoolVarP(
+		&options.noCache,
+		"no-cache",
+		"",
+		false,
+		"Disable the on-disk chart cache even when --cache-dir is set",
+	)
+	command.PersistentFlags().BoolVarP(
+		&options.verify,
+		"verify",
+		"",
+		false,
+		"Require chart provenance (.prov) verification for every Helm repository",
+	)
+	command.PersistentFlags().StringVarP(
+		&options.keyring,
+		"keyring",
+		"",
+		"",
+		"Default keyring used for chart provenance verification",
+	)
+	command.PersistentFlags().BoolVarP(
+		&options.verifySignatures,
+		"verify-signatures",
+		"",
+		false,
+		"Require cosign signature verification for every OCI chart artifact",
+	)
+	command.PersistentFlags().StringSliceVarP(
+		&options.cosignKeys,
+		"cosign-key",
+		"",
+		[]string{},
+		"PEM-encoded cosign public key used to verify OCI chart signatures; may be repeated. "+
+			"When unset, keyless (Fulcio/Rekor) verification is attempted instead",
+	)
+	command.PersistentFlags().StringVarP(
+		&options.cosignIdentity,
+		"cosign-identity",
+		"",
+		"",
+		"Required certificate identity (Subject) for keyless cosign verification",
+	)
+	command.PersistentFlags().StringVarP(
+		&options.cosignIdentityRegexp,
+		"cosign-identity-regexp",
+		"",
+		"",
+		"Regular expression matching the certificate identity (Subject) for keyless cosign verification",
+	)
+	command.PersistentFlags().StringVarP(
+		&options.cosignOIDCIssuer,
+		"cosign-oidc-issuer",
+		"",
+		"",
+		"Required certificate OIDC issuer for keyless cosign verification",
+	)
+	command.PersistentFlags().StringVarP(
+		&options.cosignOIDCIssuerRegexp,
+		"cosign-oidc-issuer-regexp",
+		"",
+		"",
+		"Regular expression matching the certificate OIDC issuer for keyless cosign verification",
+	)
+	command.PersistentFlags().IntVarP(
+		&options.retryMaxAttempts,
+		"retry-max-attempts",
+		"",
+		0,
+		"Maximum attempts for transient Helm repository/OCI registry errors; 0 uses the built-in default",
+	)
+	command.PersistentFlags().DurationVarP(
+		&options.retryInitialBackoff,
+		"retry-initial-backoff",
+		"",
+		0,
+		"Initial backoff between retries of a transient Helm repository/OCI registry error",
+	)
+	command.PersistentFlags().DurationVarP(
+		&options.retryMaxBackoff,
+		"retry-max-backoff",
+		"",
+		0,
+		"Maximum backoff between retries of a transient Helm repository/OCI registry error",
+	)
+	command.PersistentFlags().Float64VarP(
+		&options.retryJitter,
+		"retry-jitter",
+		"",
+		0,
+		"Fraction (0 to 1) of each retry backoff to randomize",
+	)
+	command.PersistentFlags().Float64VarP(
+		&options.rateLimit,
+		"registry-rate-limit",
+		"",
+		0,
+		"Maximum Helm repository/OCI registry requests per second per host; 0 disables rate limiting",
+	)
+	command.PersistentFlags().IntVarP(
+		&options.rateLimitBurst,
+		"registry-rate-limit-burst",
+		"",
+		1,
+		"Maximum burst size for --registry-rate-limit",
+	)
 
 	return command
 }
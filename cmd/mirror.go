@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fluxcd/pkg/git"
+	"github.com/fluxcd/pkg/git/gogit"
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/registry"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	"github.com/vladlosev/fouskoti/pkg/repository"
+	yamlutil "github.com/vladlosev/fouskoti/pkg/yaml"
+)
+
+type MirrorCommandOptions struct {
+	credentialsFileName string
+	cacheDir            string
+	to                  string
+}
+
+const MirrorCommandName = "mirror"
+
+// mirrorResult is printed as one JSON array entry per HelmRelease chart
+// mirrored, for downstream tooling to inventory what was copied.
+type mirrorResult struct {
+	Repository string `json:"repo"`
+	Chart      string `json:"chart"`
+	Version    string `json:"version"`
+	Digest     string `json:"digest"`
+	Bytes      int    `json:"bytes"`
+	Pushed     bool   `json:"pushed,omitempty"`
+}
+
+// findManifestFiles returns every *.yaml/*.yml file under root, walked
+// recursively.
+func findManifestFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to walk manifest directory %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// readManifestNodes parses every file in files into a single slice of
+// RNodes, the same representation BuildChart expects for a HelmRelease's
+// manifestNodes.
+func readManifestNodes(files []string) ([]*yaml.RNode, error) {
+	var nodes []*yaml.RNode
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read manifest %s: %w", file, err)
+		}
+		reader := kio.ByteReader{Reader: bytes.NewReader(data)}
+		fileNodes, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse manifest %s: %w", file, err)
+		}
+		nodes = append(nodes, fileNodes...)
+	}
+	return nodes, nil
+}
+
+// findSourceRepoURL looks up the URL of the repository referenced by
+// helmRelease's spec.chart.spec.sourceRef among nodes, mirroring how
+// HelmReleaseExpander resolves it internally.
+func findSourceRepoURL(nodes []*yaml.RNode, helmRelease *yaml.RNode) (string, error) {
+	repoKind, err := helmRelease.GetString("spec.chart.spec.sourceRef.kind")
+	if err != nil {
+		return "", fmt.Errorf("unable to get kind for the repository: %w", err)
+	}
+	repoName, err := helmRelease.GetString("spec.chart.spec.sourceRef.name")
+	if err != nil {
+		return "", fmt.Errorf("unable to get name for the repository: %w", err)
+	}
+	repoNamespace, err := yamlutil.GetStringOr(
+		helmRelease,
+		"spec.chart.spec.sourceRef.namespace",
+		helmRelease.GetNamespace(),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	for _, node := range nodes {
+		if node.GetKind() != repoKind || node.GetName() != repoName ||
+			node.GetNamespace() != repoNamespace {
+			continue
+		}
+		return yamlutil.GetStringOr(node, "spec.url", "")
+	}
+	return "", nil
+}
+
+// NewMirrorCommand creates the mirror command, which walks a directory of
+// Flux manifests, resolves the chart (including transitive dependencies)
+// for every HelmRelease it finds, and pre-populates the on-disk chart
+// cache at --cache-dir with the result. When --to is set, each resolved
+// chart is additionally pushed, as chart:version, to the OCI registry
+// repository it names, so operators can bundle-copy the exact set of
+// charts a cluster needs into an air-gapped registry.
+func NewMirrorCommand(options *MirrorCommandOptions) *cobra.Command {
+	command := &cobra.Command{
+		Use:   MirrorCommandName + " <manifests-directory>",
+		Short: "Pre-populates the chart cache from the HelmReleases found in a directory of manifests",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, logger := getContextAndLogger(cmd)
+			logger.Info("Starting mirror command")
+			defer logger.Info("Finished mirror command")
+
+			credentials := repository.Credentials{}
+			if options.credentialsFileName != "" {
+				credsFile, err := os.Open(options.credentialsFileName)
+				if err != nil {
+					return fmt.Errorf(
+						"unable to open credentials file %s: %w",
+						options.credentialsFileName,
+						err,
+					)
+				}
+				credentials, err = repository.ReadCredentials(credsFile)
+				if err != nil {
+					return fmt.Errorf(
+						"unable to read credentials from %s: %w",
+						options.credentialsFileName,
+						err,
+					)
+				}
+			}
+
+			files, err := findManifestFiles(args[0])
+			if err != nil {
+				return err
+			}
+			nodes, err := readManifestNodes(files)
+			if err != nil {
+				return err
+			}
+
+			expander := repository.NewHelmReleaseExpander(
+				ctx,
+				logger,
+				func(
+					path string,
+					authOpts *git.AuthOptions,
+					clientOpts ...gogit.ClientOption,
+				) (repository.GitClientInterface, error) {
+					return gogit.NewClient(path, authOpts, clientOpts...)
+				},
+				nil,
+				nil,
+				1,
+			)
+			if options.cacheDir != "" {
+				expander.SetDiskCache(options.cacheDir, 0, 0)
+			}
+
+			var registryClient *registry.Client
+			if options.to != "" {
+				registryClient, err = registry.NewClient()
+				if err != nil {
+					return fmt.Errorf("unable to create OCI registry client: %w", err)
+				}
+			}
+
+			outDir, err := os.MkdirTemp("", "fouskoti-mirror-")
+			if err != nil {
+				return fmt.Errorf("unable to create a temporary directory: %w", err)
+			}
+			defer os.RemoveAll(outDir)
+
+			var results []mirrorResult
+			for _, node := range nodes {
+				if node.GetKind() != "HelmRelease" ||
+					yamlutil.GetGroup(node) != "helm.toolkit.fluxcd.io" {
+					continue
+				}
+
+				builtChart, _, err := expander.BuildChart(credentials, node, nodes)
+				if err != nil {
+					return fmt.Errorf(
+						"unable to build chart for HelmRelease %s/%s: %w",
+						node.GetNamespace(),
+						node.GetName(),
+						err,
+					)
+				}
+
+				repoURL, err := findSourceRepoURL(nodes, node)
+				if err != nil {
+					return fmt.Errorf(
+						"unable to find source repository for HelmRelease %s/%s: %w",
+						node.GetNamespace(),
+						node.GetName(),
+						err,
+					)
+				}
+
+				tgzPath, err := chartutil.Save(builtChart, outDir)
+				if err != nil {
+					return fmt.Errorf("unable to package chart %s: %w", builtChart.Name(), err)
+				}
+				tgzData, err := os.ReadFile(tgzPath)
+				if err != nil {
+					return fmt.Errorf("unable to read packaged chart %s: %w", tgzPath, err)
+				}
+				os.Remove(tgzPath)
+				digest := sha256.Sum256(tgzData)
+
+				result := mirrorResult{
+					Repository: repoURL,
+					Chart:      builtChart.Name(),
+					Version:    builtChart.Metadata.Version,
+					Digest:     "sha256:" + hex.EncodeToString(digest[:]),
+					Bytes:      len(tgzData),
+				}
+
+				if registryClient != nil {
+					ref := fmt.Sprintf(
+						"%s/%s:%s",
+						strings.TrimSuffix(strings.TrimPrefix(options.to, "oci://"), "/"),
+						builtChart.Name(),
+						builtChart.Metadata.Version,
+					)
+					if _, err := registryClient.Push(tgzData, ref); err != nil {
+						return fmt.Errorf(
+							"unable to push chart %s to %s: %w",
+							builtChart.Name(),
+							ref,
+							err,
+						)
+					}
+					result.Pushed = true
+				}
+
+				results = append(results, result)
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(results)
+		},
+		SilenceUsage: true,
+	}
+	command.PersistentFlags().StringVarP(
+		&options.credentialsFileName,
+		"credentials-file",
+		"",
+		"",
+		"Name of the repository credentials file",
+	)
+	command.PersistentFlags().StringVarP(
+		&options.cacheDir,
+		"cache-dir",
+		"",
+		"",
+		"Directory for the persistent on-disk chart cache to pre-populate",
+	)
+	command.PersistentFlags().StringVarP(
+		&options.to,
+		"to",
+		"",
+		"",
+		"OCI registry repository to push each resolved chart to, e.g. oci://registry.example.com/charts",
+	)
+
+	return command
+}
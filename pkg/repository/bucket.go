@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	sourcev1beta2 "github.com/fluxcd/source-controller/api/v1beta2"
+	"helm.sh/helm/v3/pkg/chart"
+	helmloader "helm.sh/helm/v3/pkg/chart/loader"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// BucketClientInterface abstracts fetching a single object from a Bucket
+// source so that tests can inject a fake client the way GitClientInterface
+// is faked by GitClientMock.
+type BucketClientInterface interface {
+	Fetch(ctx context.Context, bucketURL string, key string, destDir string) error
+}
+
+type bucketClientFactoryFunc func(provider string) (BucketClientInterface, error)
+
+// genericBucketClient fetches an object over plain HTTP(S), using HTTP
+// basic auth if bucketURL carries userinfo. This covers the "generic"
+// Bucket provider; the "aws", "gcp" and "azure" providers need their
+// respective SDKs and are expected to be supplied via a custom
+// bucketClientFactoryFunc passed to NewHelmReleaseExpander.
+type genericBucketClient struct {
+	httpClient *http.Client
+}
+
+func newGenericBucketClient() *genericBucketClient {
+	return &genericBucketClient{httpClient: http.DefaultClient}
+}
+
+func (client *genericBucketClient) Fetch(
+	ctx context.Context,
+	bucketURL string,
+	key string,
+	destDir string,
+) error {
+	parsedURL, err := url.Parse(bucketURL)
+	if err != nil {
+		return fmt.Errorf("unable to parse bucket URL %s: %w", bucketURL, err)
+	}
+	parsedURL.Path = strings.TrimSuffix(parsedURL.Path, "/") + "/" + strings.TrimPrefix(key, "/")
+	username := parsedURL.User.Username()
+	password, _ := parsedURL.User.Password()
+	parsedURL.User = nil
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, parsedURL.String(), nil)
+	if err != nil {
+		return fmt.Errorf("unable to create request for %s: %w", parsedURL, err)
+	}
+	if username != "" || password != "" {
+		request.SetBasicAuth(username, password)
+	}
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("unable to fetch %s: %w", parsedURL, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s fetching %s", response.Status, parsedURL)
+	}
+
+	destPath := path.Join(destDir, path.Base(key))
+	destFile, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %w", destPath, err)
+	}
+	defer destFile.Close()
+
+	if _, err := io.Copy(destFile, response.Body); err != nil {
+		return fmt.Errorf("unable to write %s: %w", destPath, err)
+	}
+	return nil
+}
+
+func newBucketClient(provider string) (BucketClientInterface, error) {
+	switch provider {
+	case "", "generic":
+		return newGenericBucketClient(), nil
+	default:
+		return nil, fmt.Errorf(
+			"no built-in Bucket client for provider %q; inject one via the bucketClientFactory option",
+			provider,
+		)
+	}
+}
+
+type bucketRepoChartLoader struct {
+	loaderConfig
+}
+
+func newBucketRepositoryLoader(config loaderConfig) repositoryLoader {
+	return &bucketRepoChartLoader{loaderConfig: config}
+}
+
+// bucketURLForCreds builds a URL identifying the bucket by endpoint and
+// name, used both as a credential lookup key and as the on-disk cache key,
+// matching how Credentials are keyed for other repository kinds.
+func bucketURLForCreds(bucket *sourcev1beta2.Bucket) string {
+	scheme := "https"
+	if bucket.Spec.Insecure {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/%s", scheme, bucket.Spec.Endpoint, bucket.Spec.BucketName)
+}
+
+func (loader *bucketRepoChartLoader) loadRepositoryChart(
+	repoNode *yaml.RNode,
+	repoURL string,
+	parentContext *chartContext,
+	chartName string,
+	chartVersionSpec string,
+) (*chart.Chart, error) {
+	if repoNode == nil {
+		return nil, fmt.Errorf("Bucket sources must be referenced via sourceRef")
+	}
+
+	var bucket sourcev1beta2.Bucket
+	err := decodeToObject(repoNode, &bucket)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to decode Bucket %s/%s: %w",
+			repoNode.GetNamespace(),
+			repoNode.GetName(),
+			err,
+		)
+	}
+
+	bucketURL := bucketURLForCreds(&bucket)
+
+	loader.logger.
+		With(
+			"bucketURL", bucketURL,
+			"name", chartName,
+			"version", chartVersionSpec,
+		).
+		Debug("Loading chart from Bucket")
+
+	key := chartName
+	if !strings.HasSuffix(key, ".tgz") {
+		key = fmt.Sprintf("%s-%s.tgz", chartName, chartVersionSpec)
+	}
+
+	chartKey := fmt.Sprintf("%s#%s#%s", bucketURL, chartName, chartVersionSpec)
+	if chart, ok := loader.chartCache.get(chartKey); ok {
+		loader.logger.
+			With(
+				"bucketURL", bucketURL,
+				"name", chartName,
+				"version", chartVersionSpec,
+			).
+			Debug("Using chart from in-memory cache")
+		return chart, nil
+	}
+
+	destDir, err := getCachePathForRepo(loader.cacheRoot, bucketURL)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to get cache path for Bucket %s: %w",
+			bucketURL,
+			err,
+		)
+	}
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create cache dir %s: %w", destDir, err)
+	}
+
+	fetchURL := bucketURL
+	parsedURL, err := url.Parse(bucketURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse bucket URL %s: %w", bucketURL, err)
+	}
+	repoCreds, err := loader.credentials.FindForRepo(parsedURL)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to find credentials for bucket %s: %w",
+			bucketURL,
+			err,
+		)
+	}
+	if repoCreds != nil {
+		username := repoCreds.Credentials["username"]
+		password := repoCreds.Credentials["password"]
+		if username != "" || password != "" {
+			parsedURL.User = url.UserPassword(username, password)
+			fetchURL = parsedURL.String()
+		}
+	}
+
+	factory := loader.bucketClientFactory
+	if factory == nil {
+		factory = newBucketClient
+	}
+	client, err := factory(bucket.Spec.Provider)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to create Bucket client for %s: %w",
+			bucketURL,
+			err,
+		)
+	}
+
+	// Serialize the actual fetch by destination path so that concurrent
+	// HelmRelease expansions targeting the same chart object share a
+	// single download.
+	_, release, err := loader.repoLock.Acquire(
+		path.Join(destDir, key),
+		func() (any, func(), error) {
+			err := client.Fetch(loader.ctx, fetchURL, key, destDir)
+			return nil, nil, err
+		},
+	)
+	release()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to fetch chart %s from Bucket %s: %w",
+			key,
+			bucketURL,
+			err,
+		)
+	}
+
+	chartData, err := os.ReadFile(path.Join(destDir, path.Base(key)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read fetched chart %s: %w", key, err)
+	}
+
+	chartArchive, err := helmloader.LoadArchive(bytes.NewReader(chartData))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to load chart %s/%s from Bucket %s: %w",
+			chartName,
+			chartVersionSpec,
+			bucketURL,
+			err,
+		)
+	}
+
+	err = loadChartDependencies(loader.loaderConfig, chartArchive, nil)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to load chart dependencies for %s/%s in %s: %w",
+			chartName,
+			chartArchive.Metadata.Version,
+			bucketURL,
+			err,
+		)
+	}
+
+	loader.chartCache.set(chartKey, chartArchive)
+
+	loader.logger.
+		With(
+			"bucketURL", bucketURL,
+			"name", chartName,
+			"version", chartArchive.Metadata.Version,
+		).
+		Debug("Finished loading chart")
+	return chartArchive, nil
+}
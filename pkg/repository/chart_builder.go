@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"fmt"
+	"log/slog"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// DependencySourceKind classifies how loadChartDependencies resolved a
+// single Chart.yaml dependency's repository field.
+type DependencySourceKind string
+
+const (
+	// DependencySourceBundled marks a dependency chart already embedded
+	// under charts/ in the parent chart archive; it has no repository
+	// field and nothing is fetched for it.
+	DependencySourceBundled DependencySourceKind = "bundled"
+	// DependencySourceLocal marks a dependency resolved from a
+	// file://-or-relative path alongside the parent chart.
+	DependencySourceLocal DependencySourceKind = "local"
+	// DependencySourceAlias marks a dependency whose repository field is
+	// an alias:<name> reference to a repository object declared
+	// elsewhere in the input manifest.
+	DependencySourceAlias DependencySourceKind = "alias"
+	// DependencySourceGit marks a dependency fetched from a Git
+	// repository URL.
+	DependencySourceGit DependencySourceKind = "git"
+	// DependencySourceHelm marks a dependency fetched from an HTTP(S)
+	// Helm chart repository.
+	DependencySourceHelm DependencySourceKind = "helm"
+	// DependencySourceOCI marks a dependency fetched from an OCI
+	// registry.
+	DependencySourceOCI DependencySourceKind = "oci"
+)
+
+// DependencyReport describes how loadChartDependencies resolved one
+// Chart.yaml dependency, for callers that want to display or log
+// resolution details.
+type DependencyReport struct {
+	Name            string
+	VersionSpec     string
+	ResolvedVersion string
+	Source          DependencySourceKind
+	Repository      string
+	CacheHit        bool
+}
+
+// BuildReport summarizes how a chart and its transitive dependencies were
+// resolved. Dependencies is in resolution order and includes transitive
+// (dependency-of-a-dependency) entries.
+type BuildReport struct {
+	ChartName    string
+	ChartVersion string
+	Dependencies []DependencyReport
+}
+
+// repositorySourceKinds lists the object kinds findAliasedRepoNode will
+// match against; it mirrors the cases handled by getRepoFactory.
+var repositorySourceKinds = map[string]bool{
+	"GitRepository":  true,
+	"HelmRepository": true,
+	"OCIRepository":  true,
+	"Bucket":         true,
+}
+
+// findAliasedRepoNode looks up a repository object declared in the input
+// manifest by name, for Chart.yaml dependencies written as alias:<name>.
+func findAliasedRepoNode(repoNodes []*yaml.RNode, name string) (*yaml.RNode, error) {
+	for _, node := range repoNodes {
+		if repositorySourceKinds[node.GetKind()] && node.GetName() == name {
+			return node, nil
+		}
+	}
+	return nil, fmt.Errorf(
+		"no GitRepository, HelmRepository, OCIRepository or Bucket named %q declared in the input manifest",
+		name,
+	)
+}
+
+// markSeen records that resolvedChart was just resolved while building a
+// chart, returning true if this exact chart was already resolved earlier
+// in the same build (served from the in-memory chart cache, or
+// referenced as a dependency more than once). It is used to populate
+// DependencyReport.CacheHit; config.seenCharts is nil outside of a
+// build that collects a BuildReport, in which case it always reports a
+// miss.
+func (config loaderConfig) markSeen(resolvedChart *chart.Chart) bool {
+	if config.seenCharts == nil {
+		return false
+	}
+	_, hit := config.seenCharts[resolvedChart]
+	config.seenCharts[resolvedChart] = struct{}{}
+	return hit
+}
+
+// recordDependencyResolution appends entry to config.buildReport if the
+// current build is collecting one; it is a no-op otherwise.
+func recordDependencyResolution(config loaderConfig, entry DependencyReport) {
+	if config.buildReport == nil {
+		return
+	}
+	*config.buildReport = append(*config.buildReport, entry)
+}
+
+// logBuildReport logs one Debug record per resolved dependency, so that
+// users can debug dependency resolution without inspecting BuildReport
+// programmatically.
+func logBuildReport(logger *slog.Logger, report BuildReport) {
+	for _, dependency := range report.Dependencies {
+		logger.
+			With(
+				"chart", report.ChartName,
+				"dependency", dependency.Name,
+				"versionSpec", dependency.VersionSpec,
+				"resolvedVersion", dependency.ResolvedVersion,
+				"source", string(dependency.Source),
+				"repository", dependency.Repository,
+				"cacheHit", dependency.CacheHit,
+			).
+			Debug("Resolved chart dependency")
+	}
+}
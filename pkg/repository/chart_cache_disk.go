@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+	helmloader "helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// diskChartCacheEntry records when a chart tarball was cached for a given
+// (repoURL, chartName, version) key, and the content digest addressing its
+// blob under the cache directory.
+type diskChartCacheEntry struct {
+	Digest   string    `json:"digest"`
+	StoredAt time.Time `json:"storedAt"`
+}
+
+// diskChartCache is a content-addressable, on-disk cache of downloaded
+// chart tarballs rooted at dir. Unlike chartCacheStore, which is rebuilt
+// for every ExpandHelmReleases call, dir is expected to persist across
+// invocations (see --cache-dir), so repeated expansions of the same
+// manifests do not re-download unchanged charts. Entries older than ttl
+// are treated as absent; ttl <= 0 means entries never expire. When the
+// total size of the blobs directory would exceed maxBytes after storing a
+// new chart, the least-recently-stored index entries (and the blobs left
+// unreferenced as a result) are evicted until it fits; maxBytes <= 0
+// disables size-based eviction.
+type diskChartCache struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	mu       sync.Mutex
+}
+
+func newDiskChartCache(dir string, ttl time.Duration, maxBytes int64) *diskChartCache {
+	return &diskChartCache{dir: dir, ttl: ttl, maxBytes: maxBytes}
+}
+
+func diskChartCacheKey(repoURL, chartName, version string) string {
+	return fmt.Sprintf("%s#%s#%s", repoURL, chartName, version)
+}
+
+func (cache *diskChartCache) indexPath() string {
+	return path.Join(cache.dir, "index.json")
+}
+
+func (cache *diskChartCache) blobPath(digest string) string {
+	return path.Join(cache.dir, "blobs", digest[:2], digest+".tgz")
+}
+
+func (cache *diskChartCache) loadIndex() (map[string]diskChartCacheEntry, error) {
+	index := map[string]diskChartCacheEntry{}
+	data, err := os.ReadFile(cache.indexPath())
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to read chart cache index %s: %w",
+			cache.indexPath(),
+			err,
+		)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf(
+			"unable to parse chart cache index %s: %w",
+			cache.indexPath(),
+			err,
+		)
+	}
+	return index, nil
+}
+
+func (cache *diskChartCache) saveIndex(index map[string]diskChartCacheEntry) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode chart cache index: %w", err)
+	}
+	if err := os.MkdirAll(cache.dir, 0755); err != nil {
+		return fmt.Errorf("unable to create chart cache directory %s: %w", cache.dir, err)
+	}
+	if err := os.WriteFile(cache.indexPath(), data, 0600); err != nil {
+		return fmt.Errorf("unable to write chart cache index %s: %w", cache.indexPath(), err)
+	}
+	return nil
+}
+
+// get returns the chart cached for (repoURL, chartName, version), if a
+// live entry and its blob both exist on disk. A nil *diskChartCache
+// disables the cache, mirroring chartCacheStore's nil convention.
+func (cache *diskChartCache) get(repoURL, chartName, version string) (*chart.Chart, bool) {
+	if cache == nil {
+		return nil, false
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	index, err := cache.loadIndex()
+	if err != nil {
+		return nil, false
+	}
+	entry, ok := index[diskChartCacheKey(repoURL, chartName, version)]
+	if !ok {
+		return nil, false
+	}
+	if cache.ttl > 0 && time.Since(entry.StoredAt) > cache.ttl {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(cache.blobPath(entry.Digest))
+	if err != nil {
+		return nil, false
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != entry.Digest {
+		// The blob has been truncated or corrupted since it was stored;
+		// treat it as a miss so the caller re-downloads it rather than
+		// silently loading and rendering bad data.
+		return nil, false
+	}
+	result, err := helmloader.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+	return result, true
+}
+
+// set stores data (a chart tarball) addressed by its content digest, and
+// records it in the index under (repoURL, chartName, version). Storing the
+// same bytes under a different key reuses the existing blob.
+func (cache *diskChartCache) set(repoURL, chartName, version string, data []byte) error {
+	if cache == nil {
+		return nil
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+
+	blobPath := cache.blobPath(digest)
+	if _, err := os.Stat(blobPath); os.IsNotExist(err) {
+		if err := os.MkdirAll(path.Dir(blobPath), 0755); err != nil {
+			return fmt.Errorf("unable to create chart cache blob directory: %w", err)
+		}
+		if err := os.WriteFile(blobPath, data, 0600); err != nil {
+			return fmt.Errorf("unable to write chart cache blob %s: %w", blobPath, err)
+		}
+	}
+
+	index, err := cache.loadIndex()
+	if err != nil {
+		return err
+	}
+	index[diskChartCacheKey(repoURL, chartName, version)] = diskChartCacheEntry{
+		Digest:   digest,
+		StoredAt: time.Now(),
+	}
+	index = cache.evictToFit(index)
+	if err := cache.saveIndex(index); err != nil {
+		return err
+	}
+	return cache.removeUnreferencedBlobs(index)
+}
+
+// evictToFit removes the least-recently-stored entries of index, oldest
+// first, until the blobs still referenced by what remains fit within
+// cache.maxBytes. It is a no-op when maxBytes <= 0.
+func (cache *diskChartCache) evictToFit(
+	index map[string]diskChartCacheEntry,
+) map[string]diskChartCacheEntry {
+	if cache.maxBytes <= 0 {
+		return index
+	}
+
+	keys := make([]string, 0, len(index))
+	for key := range index {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return index[keys[i]].StoredAt.Before(index[keys[j]].StoredAt)
+	})
+
+	digestSizes := map[string]int64{}
+	referenceCounts := map[string]int{}
+	for _, key := range keys {
+		digest := index[key].Digest
+		referenceCounts[digest]++
+		if _, ok := digestSizes[digest]; ok {
+			continue
+		}
+		if info, err := os.Stat(cache.blobPath(digest)); err == nil {
+			digestSizes[digest] = info.Size()
+		}
+	}
+
+	var total int64
+	for _, size := range digestSizes {
+		total += size
+	}
+
+	for _, key := range keys {
+		if total <= cache.maxBytes {
+			break
+		}
+		digest := index[key].Digest
+		delete(index, key)
+		referenceCounts[digest]--
+		if referenceCounts[digest] == 0 {
+			total -= digestSizes[digest]
+		}
+	}
+	return index
+}
+
+// removeUnreferencedBlobs deletes any blob under the cache's blobs
+// directory whose digest is no longer referenced by index.
+func (cache *diskChartCache) removeUnreferencedBlobs(
+	index map[string]diskChartCacheEntry,
+) error {
+	liveDigests := map[string]struct{}{}
+	for _, entry := range index {
+		liveDigests[entry.Digest] = struct{}{}
+	}
+
+	blobsDir := path.Join(cache.dir, "blobs")
+	shards, err := os.ReadDir(blobsDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to list chart cache blobs directory %s: %w", blobsDir, err)
+	}
+	for _, shard := range shards {
+		if !shard.IsDir() {
+			continue
+		}
+		shardPath := path.Join(blobsDir, shard.Name())
+		blobs, err := os.ReadDir(shardPath)
+		if err != nil {
+			continue
+		}
+		for _, blob := range blobs {
+			digest := strings.TrimSuffix(blob.Name(), ".tgz")
+			if _, live := liveDigests[digest]; !live {
+				_ = os.Remove(path.Join(shardPath, blob.Name()))
+			}
+		}
+	}
+	return nil
+}
+
+// gc prunes index entries older than ttl as of now, along with any blobs
+// left unreferenced as a result. It is a no-op when ttl <= 0.
+func (cache *diskChartCache) gc(now time.Time) error {
+	if cache == nil {
+		return nil
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.ttl <= 0 {
+		return nil
+	}
+
+	index, err := cache.loadIndex()
+	if err != nil {
+		return err
+	}
+
+	for key, entry := range index {
+		if now.Sub(entry.StoredAt) > cache.ttl {
+			delete(index, key)
+		}
+	}
+	if err := cache.saveIndex(index); err != nil {
+		return err
+	}
+	return cache.removeUnreferencedBlobs(index)
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+)
+
+func createTestChartTarball(g gomega.Gomega, name string, version string) []byte {
+	dir, err := os.MkdirTemp("", "")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	defer os.RemoveAll(dir)
+
+	err = createChartArchive(name, version, map[string]string{
+		"Chart.yaml": "name: " + name + "\nversion: " + version + "\n",
+	}, dir)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	data, err := os.ReadFile(path.Join(dir, name+"-"+version+".tgz"))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	return data
+}
+
+var _ = ginkgo.Describe("On-disk chart cache", func() {
+	var g gomega.Gomega
+	var dir string
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+
+		var err error
+		dir, err = os.MkdirTemp("", "")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+	})
+
+	ginkgo.AfterEach(func() {
+		g.Expect(os.RemoveAll(dir)).To(gomega.Succeed())
+	})
+
+	ginkgo.It("reports a miss when nothing has been cached", func() {
+		cache := newDiskChartCache(dir, 0, 0)
+		_, ok := cache.get("https://example.com/repo", "test-chart", "0.1.0")
+		g.Expect(ok).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("round-trips a chart tarball through set and get", func() {
+		cache := newDiskChartCache(dir, 0, 0)
+		tarball := createTestChartTarball(g, "test-chart", "0.1.0")
+
+		err := cache.set("https://example.com/repo", "test-chart", "0.1.0", tarball)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+
+		chart, ok := cache.get("https://example.com/repo", "test-chart", "0.1.0")
+		g.Expect(ok).To(gomega.BeTrue())
+		g.Expect(chart.Name()).To(gomega.Equal("test-chart"))
+		g.Expect(chart.Metadata.Version).To(gomega.Equal("0.1.0"))
+	})
+
+	ginkgo.It("shares one blob when the same content is stored under different keys", func() {
+		cache := newDiskChartCache(dir, 0, 0)
+		tarball := createTestChartTarball(g, "test-chart", "0.1.0")
+
+		g.Expect(cache.set("https://example.com/repo-a", "test-chart", "0.1.0", tarball)).To(gomega.Succeed())
+		g.Expect(cache.set("https://example.com/repo-b", "test-chart", "0.1.0", tarball)).To(gomega.Succeed())
+
+		index, err := cache.loadIndex()
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(index).To(gomega.HaveLen(2))
+
+		entryA := index[diskChartCacheKey("https://example.com/repo-a", "test-chart", "0.1.0")]
+		entryB := index[diskChartCacheKey("https://example.com/repo-b", "test-chart", "0.1.0")]
+		g.Expect(entryA.Digest).To(gomega.Equal(entryB.Digest))
+	})
+
+	ginkgo.It("treats entries older than the TTL as expired", func() {
+		cache := newDiskChartCache(dir, time.Minute, 0)
+		tarball := createTestChartTarball(g, "test-chart", "0.1.0")
+		g.Expect(cache.set("https://example.com/repo", "test-chart", "0.1.0", tarball)).To(gomega.Succeed())
+
+		index, err := cache.loadIndex()
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		key := diskChartCacheKey("https://example.com/repo", "test-chart", "0.1.0")
+		entry := index[key]
+		entry.StoredAt = time.Now().Add(-time.Hour)
+		index[key] = entry
+		g.Expect(cache.saveIndex(index)).To(gomega.Succeed())
+
+		_, ok := cache.get("https://example.com/repo", "test-chart", "0.1.0")
+		g.Expect(ok).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("removes expired entries and unreferenced blobs on gc", func() {
+		cache := newDiskChartCache(dir, time.Minute, 0)
+		tarball := createTestChartTarball(g, "test-chart", "0.1.0")
+		g.Expect(cache.set("https://example.com/repo", "test-chart", "0.1.0", tarball)).To(gomega.Succeed())
+
+		index, err := cache.loadIndex()
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		key := diskChartCacheKey("https://example.com/repo", "test-chart", "0.1.0")
+		entry := index[key]
+		blobPath := cache.blobPath(entry.Digest)
+		entry.StoredAt = time.Now().Add(-time.Hour)
+		index[key] = entry
+		g.Expect(cache.saveIndex(index)).To(gomega.Succeed())
+
+		g.Expect(cache.gc(time.Now())).To(gomega.Succeed())
+
+		index, err = cache.loadIndex()
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(index).To(gomega.BeEmpty())
+		_, err = os.Stat(blobPath)
+		g.Expect(os.IsNotExist(err)).To(gomega.BeTrue())
+	})
+
+	ginkgo.It("disables itself entirely with a nil receiver", func() {
+		var cache *diskChartCache
+		_, ok := cache.get("https://example.com/repo", "test-chart", "0.1.0")
+		g.Expect(ok).To(gomega.BeFalse())
+		g.Expect(cache.set("https://example.com/repo", "test-chart", "0.1.0", []byte("data"))).To(gomega.Succeed())
+		g.Expect(cache.gc(time.Now())).To(gomega.Succeed())
+	})
+
+	ginkgo.It("reports a miss and does not error when a cached blob is corrupted", func() {
+		cache := newDiskChartCache(dir, 0, 0)
+		tarball := createTestChartTarball(g, "test-chart", "0.1.0")
+		g.Expect(cache.set("https://example.com/repo", "test-chart", "0.1.0", tarball)).To(gomega.Succeed())
+
+		index, err := cache.loadIndex()
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		entry := index[diskChartCacheKey("https://example.com/repo", "test-chart", "0.1.0")]
+		g.Expect(os.WriteFile(cache.blobPath(entry.Digest), []byte("not a tarball"), 0600)).To(gomega.Succeed())
+
+		_, ok := cache.get("https://example.com/repo", "test-chart", "0.1.0")
+		g.Expect(ok).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("reports a miss when a cached blob's bytes no longer match its digest", func() {
+		cache := newDiskChartCache(dir, 0, 0)
+		tarball := createTestChartTarball(g, "test-chart", "0.1.0")
+		g.Expect(cache.set("https://example.com/repo", "test-chart", "0.1.0", tarball)).To(gomega.Succeed())
+
+		index, err := cache.loadIndex()
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		entry := index[diskChartCacheKey("https://example.com/repo", "test-chart", "0.1.0")]
+
+		// Flip a single byte, simulating bit rot: the file is still the
+		// same length and may well still parse as a (different) archive,
+		// so only an explicit digest comparison on read can catch it.
+		corrupted := append([]byte(nil), tarball...)
+		corrupted[0] ^= 0xff
+		g.Expect(os.WriteFile(cache.blobPath(entry.Digest), corrupted, 0600)).To(gomega.Succeed())
+
+		_, ok := cache.get("https://example.com/repo", "test-chart", "0.1.0")
+		g.Expect(ok).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("evicts the least-recently-stored chart once maxBytes is exceeded", func() {
+		tarballA := createTestChartTarball(g, "chart-a", "0.1.0")
+		tarballB := createTestChartTarball(g, "chart-b", "0.1.0")
+		cache := newDiskChartCache(dir, 0, int64(len(tarballB)))
+
+		g.Expect(cache.set("https://example.com/repo", "chart-a", "0.1.0", tarballA)).To(gomega.Succeed())
+		g.Expect(cache.set("https://example.com/repo", "chart-b", "0.1.0", tarballB)).To(gomega.Succeed())
+
+		_, ok := cache.get("https://example.com/repo", "chart-a", "0.1.0")
+		g.Expect(ok).To(gomega.BeFalse())
+
+		chart, ok := cache.get("https://example.com/repo", "chart-b", "0.1.0")
+		g.Expect(ok).To(gomega.BeTrue())
+		g.Expect(chart.Name()).To(gomega.Equal("chart-b"))
+	})
+
+	ginkgo.It("survives concurrent loads of the same chart", func() {
+		cache := newDiskChartCache(dir, 0, 0)
+		tarball := createTestChartTarball(g, "test-chart", "0.1.0")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer ginkgo.GinkgoRecover()
+				g.Expect(cache.set("https://example.com/repo", "test-chart", "0.1.0", tarball)).To(gomega.Succeed())
+				_, ok := cache.get("https://example.com/repo", "test-chart", "0.1.0")
+				g.Expect(ok).To(gomega.BeTrue())
+			}()
+		}
+		wg.Wait()
+	})
+})
@@ -0,0 +1,86 @@
+package repository
+
+import "helm.sh/helm/v3/pkg/chart"
+
+// deepCopyChart returns a copy of src safe to pass to
+// chartutil.ProcessDependenciesWithMerge, which mutates a chart's
+// Metadata.Dependencies entries and dependency list in place (enabling or
+// disabling subcharts based on the values it's given). Without this, two
+// HelmReleases that share a cached chart (see chartCacheStore) would race
+// over the same *chart.Chart and could each see the other's
+// condition/tags decisions. Raw/Templates/Files/Schema/Lock are shared by
+// reference since nothing in the expansion path mutates them; Metadata,
+// its Dependencies, Values and the subchart tree are deep-copied since
+// those are exactly what gets mutated.
+func deepCopyChart(src *chart.Chart) *chart.Chart {
+	if src == nil {
+		return nil
+	}
+
+	dst := &chart.Chart{
+		Raw:       src.Raw,
+		Lock:      src.Lock,
+		Templates: src.Templates,
+		Schema:    src.Schema,
+		Files:     src.Files,
+		Metadata:  deepCopyChartMetadata(src.Metadata),
+		Values:    deepCopyValue(src.Values).(map[string]interface{}),
+	}
+
+	subcharts := src.Dependencies()
+	copies := make([]*chart.Chart, len(subcharts))
+	for i, subchart := range subcharts {
+		copies[i] = deepCopyChart(subchart)
+	}
+	dst.SetDependencies(copies...)
+
+	return dst
+}
+
+// deepCopyChartMetadata copies md along with its Dependencies slice, whose
+// *chart.Dependency entries get their Enabled field flipped in place by
+// chartutil.ProcessDependenciesWithMerge.
+func deepCopyChartMetadata(md *chart.Metadata) *chart.Metadata {
+	if md == nil {
+		return nil
+	}
+
+	copied := *md
+	if md.Dependencies != nil {
+		copied.Dependencies = make([]*chart.Dependency, len(md.Dependencies))
+		for i, dep := range md.Dependencies {
+			depCopy := *dep
+			if dep.Tags != nil {
+				depCopy.Tags = append([]string(nil), dep.Tags...)
+			}
+			if dep.ImportValues != nil {
+				depCopy.ImportValues = append([]interface{}(nil), dep.ImportValues...)
+			}
+			copied.Dependencies[i] = &depCopy
+		}
+	}
+	return &copied
+}
+
+// deepCopyValue recursively copies the maps and slices chart Values trees
+// are built from (the output of a YAML/JSON unmarshal), so that mutating
+// the copy never touches src. Other values (strings, numbers, bools, nil)
+// are immutable and are returned as-is.
+func deepCopyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = deepCopyValue(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopyValue(item)
+		}
+		return copied
+	default:
+		return v
+	}
+}
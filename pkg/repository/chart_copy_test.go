@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func mustBuildChartWithSubchart(g gomega.Gomega, condition string) *chart.Chart {
+	subchart := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "sub", Version: "1.0.0", APIVersion: chart.APIVersionV2},
+		Values:   map[string]interface{}{},
+	}
+	top := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "top",
+			Version:    "1.0.0",
+			APIVersion: chart.APIVersionV2,
+			Dependencies: []*chart.Dependency{
+				{Name: "sub", Version: "1.0.0", Condition: condition},
+			},
+		},
+		Values: map[string]interface{}{
+			"sub": map[string]interface{}{"enabled": true},
+		},
+	}
+	top.SetDependencies(subchart)
+	g.Expect(top.Validate()).To(gomega.Succeed())
+	return top
+}
+
+var _ = ginkgo.Describe("deepCopyChart", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("returns a chart whose dependency list is independent of the source", func() {
+		src := mustBuildChartWithSubchart(g, "sub.enabled")
+		dst := deepCopyChart(src)
+
+		g.Expect(dst.Metadata.Dependencies).To(gomega.HaveLen(1))
+		g.Expect(dst.Metadata.Dependencies[0]).ToNot(gomega.BeIdenticalTo(src.Metadata.Dependencies[0]))
+		g.Expect(dst.Dependencies()).To(gomega.HaveLen(1))
+		g.Expect(dst.Dependencies()[0]).ToNot(gomega.BeIdenticalTo(src.Dependencies()[0]))
+	})
+
+	ginkgo.It("isolates separate ProcessDependenciesWithMerge calls against the same cached chart", func() {
+		cached := mustBuildChartWithSubchart(g, "sub.enabled")
+
+		enabledCopy := deepCopyChart(cached)
+		err := chartutil.ProcessDependenciesWithMerge(
+			enabledCopy,
+			map[string]interface{}{"sub": map[string]interface{}{"enabled": true}},
+		)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(enabledCopy.Dependencies()).To(gomega.HaveLen(1))
+
+		disabledCopy := deepCopyChart(cached)
+		err = chartutil.ProcessDependenciesWithMerge(
+			disabledCopy,
+			map[string]interface{}{"sub": map[string]interface{}{"enabled": false}},
+		)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(disabledCopy.Dependencies()).To(gomega.BeEmpty())
+
+		// The shared cached chart, and any further copy made from it, must
+		// still reflect the original, unmutated dependency.
+		g.Expect(cached.Dependencies()).To(gomega.HaveLen(1))
+		g.Expect(cached.Metadata.Dependencies[0].Enabled).To(gomega.BeFalse())
+
+		freshCopy := deepCopyChart(cached)
+		err = chartutil.ProcessDependenciesWithMerge(
+			freshCopy,
+			map[string]interface{}{"sub": map[string]interface{}{"enabled": true}},
+		)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(freshCopy.Dependencies()).To(gomega.HaveLen(1))
+	})
+
+	ginkgo.It("deep-copies Values so mutating the copy leaves the source untouched", func() {
+		src := mustBuildChartWithSubchart(g, "sub.enabled")
+		dst := deepCopyChart(src)
+
+		dst.Values["sub"].(map[string]interface{})["enabled"] = false
+
+		g.Expect(src.Values["sub"].(map[string]interface{})["enabled"]).To(gomega.BeTrue())
+	})
+
+	ginkgo.It("returns nil for a nil chart", func() {
+		gomega.Expect(deepCopyChart(nil)).To(gomega.BeNil())
+	})
+})
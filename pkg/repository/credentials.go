@@ -5,29 +5,101 @@ import (
 	"io"
 	"net/url"
 	"os"
+	"path"
 	"strings"
 
 	"golang.org/x/exp/maps"
 	"gopkg.in/yaml.v3"
 )
 
-type RepositoryCreds map[string]string
+type RepositoryCreds struct {
+	Credentials map[string]string
+	// TLSClientCertData and TLSClientCertKey hold a PEM-encoded client
+	// certificate and key presented for mutual TLS authentication
+	// against Git, Helm, and OCI repositories, mirroring the
+	// tls.crt/tls.key entries of a kubernetes.io/tls Secret referenced
+	// by a Flux source's certSecretRef.
+	TLSClientCertData string `yaml:"tlsClientCertData,omitempty"`
+	TLSClientCertKey  string `yaml:"tlsClientCertKey,omitempty"`
+	// CAData holds a PEM-encoded CA bundle used to verify the server
+	// certificate presented by Git, Helm, and OCI repositories over TLS.
+	CAData string `yaml:"caData,omitempty"`
+	// OCIToken is a bearer/identity token used to log into an OCI
+	// registry in place of a username/password pair.
+	OCIToken string `yaml:"ociToken,omitempty"`
+	// URLPattern, when set, is matched against a repository URL instead
+	// of this entry's map key, allowing one set of credentials to cover
+	// several chart paths on the same host. It is a path prefix or a
+	// glob pattern (as accepted by path.Match) applied to the URL's
+	// path, and its host component may start with "*." to match any
+	// subdomain of the rest of the host, e.g. "*.example.com". See
+	// Credentials.FindForRepo for how entries are ranked against each
+	// other.
+	URLPattern string `yaml:"urlPattern,omitempty"`
+	// VerificationPublicKeys holds one or more PEM-encoded cosign public
+	// keys used to verify OCI chart artifacts from this repository,
+	// overriding the global --cosign-key default (if any) set via
+	// HelmReleaseExpander.SetChartVerification. A chart is accepted if any
+	// one of them verifies it.
+	VerificationPublicKeys []string `yaml:"verificationPublicKeys,omitempty"`
+}
+
+// firstNonEmpty returns the first of values that is not the empty
+// string, or "" if all of them are. It is used to prefer an explicit
+// RepositoryCreds field over its legacy generic-map equivalent.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
 
+func expandEnvVar(value string) string {
+	if rest, found := strings.CutPrefix(value, "$"); found && len(rest) > 0 {
+		return os.Getenv(rest)
+	}
+	return value
+}
+
+// AsBytesMap converts creds to the map[string][]byte shape expected by
+// git.NewAuthOptions, merging the generic Credentials map with the
+// explicit TLS fields under the key names it recognizes for CA and
+// client-certificate material.
 func (creds RepositoryCreds) AsBytesMap() map[string][]byte {
 	result := map[string][]byte{}
 
-	for key, value := range creds {
+	for key, value := range creds.Credentials {
 		result[key] = []byte(value)
 	}
+	if creds.CAData != "" {
+		result["caFile"] = []byte(creds.CAData)
+	}
+	if creds.TLSClientCertData != "" {
+		result["certFile"] = []byte(creds.TLSClientCertData)
+	}
+	if creds.TLSClientCertKey != "" {
+		result["keyFile"] = []byte(creds.TLSClientCertKey)
+	}
 	return result
 }
 
-func (creds RepositoryCreds) expandEnvVars() {
-	for _, key := range maps.Keys(creds) {
-		value := creds[key]
-		if rest, found := strings.CutPrefix(value, "$"); found && len(rest) > 0 {
-			creds[key] = os.Getenv(rest)
-		}
+// expandEnvVars expands "$VAR"-shaped values in place. It takes a
+// pointer receiver because, unlike the Credentials map (whose entries
+// are shared through the map even when RepositoryCreds is copied), the
+// explicit string fields would otherwise be expanded on a throwaway
+// copy.
+func (creds *RepositoryCreds) expandEnvVars() {
+	for _, key := range maps.Keys(creds.Credentials) {
+		creds.Credentials[key] = expandEnvVar(creds.Credentials[key])
+	}
+	creds.TLSClientCertData = expandEnvVar(creds.TLSClientCertData)
+	creds.TLSClientCertKey = expandEnvVar(creds.TLSClientCertKey)
+	creds.CAData = expandEnvVar(creds.CAData)
+	creds.OCIToken = expandEnvVar(creds.OCIToken)
+	for i, key := range creds.VerificationPublicKeys {
+		creds.VerificationPublicKeys[i] = expandEnvVar(key)
 	}
 }
 
@@ -45,32 +117,110 @@ func ReadCredentials(input io.Reader) (Credentials, error) {
 		return nil, fmt.Errorf("unable to parse credentials YAML: %w", err)
 	}
 
-	for _, value := range credentials {
+	for key, value := range credentials {
 		value.expandEnvVars()
+		credentials[key] = value
 	}
 	return credentials, nil
 }
 
+// hostMatchesPattern reports whether host satisfies patternHost, which may
+// start with "*." to match any subdomain of the rest of patternHost (e.g.
+// "*.example.com" matches "charts.example.com" and "a.b.example.com", as
+// well as "example.com" itself).
+func hostMatchesPattern(patternHost, host string) bool {
+	if domain, ok := strings.CutPrefix(patternHost, "*."); ok {
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	}
+	return patternHost == host
+}
+
+// matchURLPattern reports whether repoURL is covered by pattern, a
+// urlPattern-style glob or path prefix as documented on
+// RepositoryCreds.URLPattern. On a match, it also returns a specificity
+// score (the length of the pattern's fixed path prefix) so callers can
+// prefer the most specific of several matching patterns.
+func matchURLPattern(pattern string, repoURL *url.URL) (int, bool) {
+	parsedPattern, err := url.Parse(pattern)
+	if err != nil {
+		return 0, false
+	}
+	if parsedPattern.Scheme != repoURL.Scheme ||
+		!hostMatchesPattern(parsedPattern.Host, repoURL.Host) {
+		return 0, false
+	}
+
+	patternPath := parsedPattern.Path
+	if patternPath == "" {
+		patternPath = "/"
+	}
+	if strings.HasPrefix(repoURL.Path, patternPath) {
+		return len(patternPath), true
+	}
+	if matched, err := path.Match(patternPath, repoURL.Path); err == nil && matched {
+		fixedPrefix := patternPath
+		if i := strings.IndexAny(patternPath, "*?[\\"); i >= 0 {
+			fixedPrefix = patternPath[:i]
+		}
+		return len(fixedPrefix), true
+	}
+	return 0, false
+}
+
+// FindForRepo looks up the credentials that apply to repoURL, in order of
+// specificity:
+//
+//  1. An entry whose map key is the exact, literal repository URL.
+//  2. Among entries whose URLPattern (or, if unset, map key) matches
+//     repoURL's scheme, host, and path, the one with the longest matching
+//     path prefix. A pattern's host may use a "*." wildcard to cover any
+//     subdomain.
+//  3. An entry matching repoURL only by scheme, host, and (if present)
+//     userinfo, ignoring its path, preserved for configurations that key
+//     credentials by repository root rather than by chart path.
+//
+// It returns a nil RepositoryCreds, without error, when nothing matches.
 func (credentials Credentials) FindForRepo(
 	repoURL *url.URL,
 ) (*RepositoryCreds, error) {
 	if creds, ok := credentials[repoURL.String()]; ok {
 		return &creds, nil
 	}
+
+	var bestMatch *RepositoryCreds
+	bestScore := -1
+	var fallbackMatch *RepositoryCreds
+
 	for storedRepoURL, creds := range credentials {
-		parsedURL, err := url.Parse(storedRepoURL)
+		creds := creds
+		pattern := creds.URLPattern
+		if pattern == "" {
+			pattern = storedRepoURL
+		}
+		parsedURL, err := url.Parse(pattern)
 		if err != nil {
 			return nil, fmt.Errorf(
-				"unable to parse configured repository URL %s:%w",
-				storedRepoURL,
+				"unable to parse configured repository URL pattern %s: %w",
+				pattern,
 				err,
 			)
 		}
-		if repoURL.Scheme == parsedURL.Scheme &&
-			repoURL.Host == parsedURL.Host &&
+
+		if score, ok := matchURLPattern(pattern, repoURL); ok && score > bestScore {
+			bestScore = score
+			bestMatch = &creds
+		}
+
+		if fallbackMatch == nil &&
+			repoURL.Scheme == parsedURL.Scheme &&
+			hostMatchesPattern(parsedURL.Host, repoURL.Host) &&
 			repoURL.User.Username() == parsedURL.User.Username() {
-			return &creds, nil
+			fallbackMatch = &creds
 		}
 	}
-	return nil, nil
+
+	if bestMatch != nil {
+		return bestMatch, nil
+	}
+	return fallbackMatch, nil
 }
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"bytes"
+	"net/url"
 	"os"
 	"strings"
 
@@ -63,4 +64,115 @@ var _ = ginkgo.Describe("repository credentials", func() {
 			"foo",
 		))
 	})
+
+	findForRepoLabel := func(g gomega.Gomega, credentials Credentials, repoURL string) string {
+		parsedURL, err := url.Parse(repoURL)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+
+		creds, err := credentials.FindForRepo(parsedURL)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		if creds == nil {
+			return ""
+		}
+		return creds.Credentials["label"]
+	}
+
+	ginkgo.It("prefers an exact URL match over any pattern match", func() {
+		credentials := Credentials{
+			"https://charts.example.com/repo": {
+				Credentials: map[string]string{"label": "exact"},
+			},
+			"https://charts.example.com/": {
+				Credentials: map[string]string{"label": "host"},
+			},
+		}
+		label := findForRepoLabel(g, credentials, "https://charts.example.com/repo")
+		g.Expect(label).To(gomega.Equal("exact"))
+	})
+
+	ginkgo.It("prefers the longest matching urlPattern path prefix", func() {
+		credentials := Credentials{
+			"broad": {
+				URLPattern: "https://charts.example.com/teams",
+				Credentials: map[string]string{
+					"label": "broad",
+				},
+			},
+			"narrow": {
+				URLPattern: "https://charts.example.com/teams/payments",
+				Credentials: map[string]string{
+					"label": "narrow",
+				},
+			},
+		}
+		label := findForRepoLabel(g, credentials, "https://charts.example.com/teams/payments/widget")
+		g.Expect(label).To(gomega.Equal("narrow"))
+	})
+
+	ginkgo.It("prefers a urlPattern path-prefix match over a host-only entry", func() {
+		credentials := Credentials{
+			"https://charts.example.com/": {
+				Credentials: map[string]string{"label": "host-only"},
+			},
+			"path-scoped": {
+				URLPattern: "https://charts.example.com/teams/payments",
+				Credentials: map[string]string{
+					"label": "path-scoped",
+				},
+			},
+		}
+		label := findForRepoLabel(g, credentials, "https://charts.example.com/teams/payments/widget")
+		g.Expect(label).To(gomega.Equal("path-scoped"))
+	})
+
+	ginkgo.It("matches a glob urlPattern", func() {
+		credentials := Credentials{
+			"glob": {
+				URLPattern: "https://charts.example.com/teams/*/widgets",
+				Credentials: map[string]string{
+					"label": "glob",
+				},
+			},
+		}
+		label := findForRepoLabel(g, credentials, "https://charts.example.com/teams/payments/widgets")
+		g.Expect(label).To(gomega.Equal("glob"))
+	})
+
+	ginkgo.It("matches a wildcard host urlPattern against a subdomain", func() {
+		credentials := Credentials{
+			"wildcard": {
+				URLPattern: "https://*.example.com/",
+				Credentials: map[string]string{
+					"label": "wildcard",
+				},
+			},
+		}
+		label := findForRepoLabel(g, credentials, "https://charts.example.com/repo")
+		g.Expect(label).To(gomega.Equal("wildcard"))
+	})
+
+	ginkgo.It("falls back to a wildcard host-level match when no path matches", func() {
+		credentials := Credentials{
+			"https://*.example.com/repo": {
+				Credentials: map[string]string{
+					"label": "wildcard-fallback",
+				},
+			},
+		}
+		label := findForRepoLabel(g, credentials, "https://charts.example.com/other")
+		g.Expect(label).To(gomega.Equal("wildcard-fallback"))
+	})
+
+	ginkgo.It("returns nil when no entry matches", func() {
+		credentials := Credentials{
+			"https://other.example.com/": {
+				Credentials: map[string]string{"label": "other"},
+			},
+		}
+		parsedURL, err := url.Parse("https://charts.example.com/repo")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		creds, err := credentials.FindForRepo(parsedURL)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(creds).To(gomega.BeNil())
+	})
 })
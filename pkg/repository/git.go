@@ -2,8 +2,11 @@ package repository
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/url"
+	"os"
 	"path"
 	"time"
 
@@ -16,6 +19,64 @@ import (
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
+// gitCloneMarkerName names the file written into a persistent clone
+// directory to record when it was last refreshed; see gitCloneIsFresh.
+const gitCloneMarkerName = ".fouskoti-cloned-at"
+
+// gitCloneCacheDir derives a stable, filesystem-safe directory for a
+// persistent clone of repoURL at ref, rooted at root. Unlike
+// getCachePathForRepo, whose path is derived from repoURL alone and reused
+// by every ref requested for that repository, this hashes repoURL together
+// with every component of ref so that clones of different branches, tags,
+// or commits of the same repository never collide.
+func gitCloneCacheDir(root string, repoURL string, ref *sourcev1.GitRepositoryRef) string {
+	key := fmt.Sprintf(
+		"%s#%s#%s#%s#%s#%s",
+		repoURL,
+		ref.Branch,
+		ref.Tag,
+		ref.SemVer,
+		ref.Name,
+		ref.Commit,
+	)
+	sum := sha256.Sum256([]byte(key))
+	return path.Join(root, hex.EncodeToString(sum[:]))
+}
+
+// gitCloneIsFresh reports whether the persistent clone at repoPath was
+// refreshed within ttl. It returns false, nil if the directory has never
+// been cloned (no marker present) and false, nil once the marker is older
+// than ttl; ttl <= 0 means a clone never goes stale. Since
+// GitClientInterface exposes no incremental fetch, staleness is always
+// resolved by discarding the directory and cloning again from scratch,
+// rather than by fetching updates into it.
+func gitCloneIsFresh(repoPath string, ttl time.Duration) (bool, error) {
+	info, err := os.Stat(path.Join(repoPath, gitCloneMarkerName))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf(
+			"unable to stat Git clone marker for %s: %w",
+			repoPath,
+			err,
+		)
+	}
+	if ttl <= 0 {
+		return true, nil
+	}
+	return time.Since(info.ModTime()) < ttl, nil
+}
+
+// touchGitCloneMarker records that repoPath was just (re)cloned.
+func touchGitCloneMarker(repoPath string) error {
+	markerPath := path.Join(repoPath, gitCloneMarkerName)
+	if err := os.WriteFile(markerPath, []byte(time.Now().UTC().Format(time.RFC3339)), 0600); err != nil {
+		return fmt.Errorf("unable to write Git clone marker %s: %w", markerPath, err)
+	}
+	return nil
+}
+
 type gitRepoChartLoader struct {
 	loaderConfig
 }
@@ -42,13 +103,28 @@ func (loader *gitRepoChartLoader) cloneRepo(
 	repo *sourcev1.GitRepository,
 	repoURL string,
 ) (string, error) {
-	repoPath, err := getCachePathForRepo(loader.cacheRoot, repoURL)
-	if err != nil {
-		return "", fmt.Errorf(
-			"unable to get cache path for Git repository %s: %w",
+	// When a persistent disk cache is configured, root the clone under it
+	// (keyed by repoURL and ref) so that it survives across invocations
+	// instead of the per-run cacheRoot, which is removed once this process
+	// exits.
+	persistent := loader.diskChartCache != nil
+	var repoPath string
+	var err error
+	if persistent {
+		repoPath = gitCloneCacheDir(
+			path.Join(loader.diskChartCache.dir, "git"),
 			repoURL,
-			err,
+			normalizeGitReference(repo.Spec.Reference),
 		)
+	} else {
+		repoPath, err = getCachePathForRepo(loader.cacheRoot, repoURL)
+		if err != nil {
+			return "", fmt.Errorf(
+				"unable to get cache path for Git repository %s: %w",
+				repoURL,
+				err,
+			)
+		}
 	}
 
 	parsedURL, err := url.Parse(repoURL)
@@ -110,37 +186,71 @@ func (loader *gitRepoChartLoader) cloneRepo(
 		timeout = specTimeout.Duration
 	}
 
-	client, err := loader.gitClientFactory(repoPath, authOpts, clientOpts...)
-	if err != nil {
-		return "", fmt.Errorf(
-			"unable to create Git client to clone repository %s: %w",
-			repoURL,
-			err,
-		)
-	}
-	cloneCtx, cancel := context.WithTimeout(loader.ctx, timeout)
-	defer cancel()
+	// Serialize the actual clone by cache directory so that concurrent
+	// HelmRelease expansions targeting the same GitRepository share a
+	// single clone instead of racing to populate repoPath.
+	_, release, err := loader.repoLock.Acquire(repoPath, func() (any, func(), error) {
+		if persistent {
+			fresh, err := gitCloneIsFresh(repoPath, loader.diskChartCache.ttl)
+			if err != nil {
+				return nil, nil, err
+			}
+			if fresh {
+				return nil, nil, nil
+			}
+			// GitClientInterface has no fetch operation, so a stale clone
+			// cannot be refreshed incrementally; discard it and clone again.
+			if err := os.RemoveAll(repoPath); err != nil {
+				return nil, nil, fmt.Errorf(
+					"unable to remove stale Git clone %s: %w",
+					repoPath,
+					err,
+				)
+			}
+		}
 
-	cloneOpts := repository.CloneConfig{
-		ShallowClone: true,
-	}
-	if repo.Spec.Reference != nil {
-		ref := normalizeGitReference(repo.Spec.Reference)
-		cloneOpts.CheckoutStrategy = repository.CheckoutStrategy{
-			Branch:  ref.Branch,
-			Tag:     ref.Tag,
-			SemVer:  ref.SemVer,
-			RefName: ref.Name,
-			Commit:  ref.Commit,
+		client, err := loader.gitClientFactory(repoPath, authOpts, clientOpts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf(
+				"unable to create Git client to clone repository %s: %w",
+				repoURL,
+				err,
+			)
 		}
-	}
-	_, err = client.Clone(cloneCtx, repoURL, cloneOpts)
+		cloneCtx, cancel := context.WithTimeout(loader.ctx, timeout)
+		defer cancel()
+
+		cloneOpts := repository.CloneConfig{
+			ShallowClone: true,
+		}
+		if repo.Spec.Reference != nil {
+			ref := normalizeGitReference(repo.Spec.Reference)
+			cloneOpts.CheckoutStrategy = repository.CheckoutStrategy{
+				Branch:  ref.Branch,
+				Tag:     ref.Tag,
+				SemVer:  ref.SemVer,
+				RefName: ref.Name,
+				Commit:  ref.Commit,
+			}
+		}
+		_, err = client.Clone(cloneCtx, repoURL, cloneOpts)
+		if err != nil {
+			return nil, nil, fmt.Errorf(
+				"unable to clone Git repository %s: %w",
+				repoURL,
+				err,
+			)
+		}
+		if persistent {
+			if err := touchGitCloneMarker(repoPath); err != nil {
+				return nil, nil, err
+			}
+		}
+		return nil, nil, nil
+	})
+	release()
 	if err != nil {
-		return "", fmt.Errorf(
-			"unable to clone Git repository %s: %w",
-			repoURL,
-			err,
-		)
+		return "", err
 	}
 	return repoPath, nil
 }
@@ -186,21 +296,19 @@ func (loader *gitRepoChartLoader) loadRepositoryChart(
 		ref.Name,
 		ref.Commit,
 	)
-	if loader.chartCache != nil {
-		if chart, ok := loader.chartCache[chartKey]; ok {
-			loader.logger.
-				With(
-					"repoURL", repoURL,
-					"name", chartName,
-					"branch", ref.Branch,
-					"tag", ref.Tag,
-					"semver", ref.SemVer,
-					"name", ref.Name,
-					"commit", ref.Commit,
-				).
-				Debug("Using chart from in-memory cache")
-			return chart, nil
-		}
+	if chart, ok := loader.chartCache.get(chartKey); ok {
+		loader.logger.
+			With(
+				"repoURL", repoURL,
+				"name", chartName,
+				"branch", ref.Branch,
+				"tag", ref.Tag,
+				"semver", ref.SemVer,
+				"name", ref.Name,
+				"commit", ref.Commit,
+			).
+			Debug("Using chart from in-memory cache")
+		return chart, nil
 	}
 
 	var repoPath string
@@ -247,9 +355,7 @@ func (loader *gitRepoChartLoader) loadRepositoryChart(
 		)
 	}
 
-	if loader.chartCache != nil {
-		loader.chartCache[chartKey] = chart
-	}
+	loader.chartCache.set(chartKey, chart)
 
 	loader.logger.
 		With(
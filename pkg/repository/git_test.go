@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"os"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+)
+
+var _ = ginkgo.Describe("gitCloneCacheDir", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("derives distinct paths for different refs of the same repository", func() {
+		root := "/cache/git"
+		repoURL := "https://example.com/repo.git"
+
+		branchDir := gitCloneCacheDir(root, repoURL, &sourcev1.GitRepositoryRef{Branch: "main"})
+		tagDir := gitCloneCacheDir(root, repoURL, &sourcev1.GitRepositoryRef{Tag: "v1.0.0"})
+
+		g.Expect(branchDir).ToNot(gomega.Equal(tagDir))
+	})
+
+	ginkgo.It("is stable for the same repository URL and ref", func() {
+		root := "/cache/git"
+		repoURL := "https://example.com/repo.git"
+		ref := &sourcev1.GitRepositoryRef{Branch: "main"}
+
+		g.Expect(gitCloneCacheDir(root, repoURL, ref)).To(gomega.Equal(gitCloneCacheDir(root, repoURL, ref)))
+	})
+})
+
+var _ = ginkgo.Describe("gitCloneIsFresh", func() {
+	var g gomega.Gomega
+	var dir string
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+
+		var err error
+		dir, err = os.MkdirTemp("", "")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+	})
+
+	ginkgo.AfterEach(func() {
+		g.Expect(os.RemoveAll(dir)).To(gomega.Succeed())
+	})
+
+	ginkgo.It("reports not fresh when no clone marker exists", func() {
+		fresh, err := gitCloneIsFresh(dir, time.Hour)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(fresh).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("reports fresh right after the marker is touched", func() {
+		g.Expect(touchGitCloneMarker(dir)).To(gomega.Succeed())
+
+		fresh, err := gitCloneIsFresh(dir, time.Hour)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(fresh).To(gomega.BeTrue())
+	})
+
+	ginkgo.It("never expires when ttl is zero", func() {
+		g.Expect(touchGitCloneMarker(dir)).To(gomega.Succeed())
+
+		fresh, err := gitCloneIsFresh(dir, 0)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(fresh).To(gomega.BeTrue())
+	})
+
+	ginkgo.It("reports stale once the marker is older than ttl", func() {
+		g.Expect(touchGitCloneMarker(dir)).To(gomega.Succeed())
+
+		markerPath := dir + "/" + gitCloneMarkerName
+		staleTime := time.Now().Add(-2 * time.Hour)
+		g.Expect(os.Chtimes(markerPath, staleTime, staleTime)).To(gomega.Succeed())
+
+		fresh, err := gitCloneIsFresh(dir, time.Hour)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(fresh).To(gomega.BeFalse())
+	})
+})
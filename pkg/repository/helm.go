@@ -1,8 +1,10 @@
 package repository
 
 import (
+	"bytes"
 	"fmt"
 	"net/url"
+	"os"
 	"path"
 	"strings"
 
@@ -11,6 +13,7 @@ import (
 	helmloader "helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/cli"
 	helmgetter "helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/provenance"
 	"helm.sh/helm/v3/pkg/registry"
 	helmrepo "helm.sh/helm/v3/pkg/repo"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
@@ -41,7 +44,209 @@ func normalizeURL(repositoryURL string) (string, error) {
 	return u.String(), nil
 }
 
-// TODO(vlad): Add caching support.
+// repoAuthConfig holds the authentication material resolved from
+// Credentials for a Helm repository: basic-auth username/password, plus
+// TLS material materialized to temporary files since the getters in
+// helm.sh/helm/v3/pkg/getter take file paths rather than raw PEM bytes.
+type repoAuthConfig struct {
+	username              string
+	password              string
+	certFile              string
+	keyFile               string
+	caFile                string
+	insecureSkipTLSverify bool
+	// verifyProvenance and keyringFile configure per-repository chart
+	// provenance verification, overriding the --verify/--keyring
+	// defaults for this repository only.
+	verifyProvenance bool
+	keyringFile      string
+	cleanup          func()
+}
+
+// materializeRepoAuthConfig looks up credentials for repoURL (username,
+// password, ca.crt, tls.crt, tls.key and insecureSkipTLSVerify) and writes
+// any certificate material it finds to a temporary directory so it can be
+// referenced by file path, mirroring how source-controller builds a
+// *tls.Config from a referenced Secret. The returned cleanup func must be
+// called once the config is no longer needed.
+func materializeRepoAuthConfig(
+	credentials Credentials,
+	repoURL string,
+) (*repoAuthConfig, error) {
+	config := &repoAuthConfig{cleanup: func() {}}
+
+	parsedURL, err := url.Parse(repoURL)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to parse repository URL %s: %w",
+			repoURL,
+			err,
+		)
+	}
+
+	repoCreds, err := credentials.FindForRepo(parsedURL)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to find credentials for repository %s: %w",
+			repoURL,
+			err,
+		)
+	}
+	if repoCreds == nil {
+		return config, nil
+	}
+
+	config.username = repoCreds.Credentials["username"]
+	config.password = repoCreds.Credentials["password"]
+	config.insecureSkipTLSverify = repoCreds.Credentials["insecureSkipTLSVerify"] == "true"
+	config.verifyProvenance = repoCreds.Credentials["verify"] == "true"
+	config.keyringFile = repoCreds.Credentials["keyring"]
+
+	// The explicit TLSClientCertData/TLSClientCertKey/CAData fields take
+	// precedence; the generic ca.crt/tls.crt/tls.key map entries remain
+	// supported for credentials files written before those fields existed.
+	files := map[string]*string{
+		"ca.crt":  &config.caFile,
+		"tls.crt": &config.certFile,
+		"tls.key": &config.keyFile,
+	}
+	material := map[string]string{
+		"ca.crt":  firstNonEmpty(repoCreds.CAData, repoCreds.Credentials["ca.crt"]),
+		"tls.crt": firstNonEmpty(repoCreds.TLSClientCertData, repoCreds.Credentials["tls.crt"]),
+		"tls.key": firstNonEmpty(repoCreds.TLSClientCertKey, repoCreds.Credentials["tls.key"]),
+	}
+	var haveMaterial bool
+	for _, value := range material {
+		if value != "" {
+			haveMaterial = true
+			break
+		}
+	}
+	if !haveMaterial {
+		return config, nil
+	}
+
+	dir, err := os.MkdirTemp("", "repo-tls-")
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to create a TLS material directory for %s: %w",
+			repoURL,
+			err,
+		)
+	}
+	config.cleanup = func() { os.RemoveAll(dir) }
+
+	for key, dest := range files {
+		value := material[key]
+		if value == "" {
+			continue
+		}
+		filePath := path.Join(dir, key)
+		if err := os.WriteFile(filePath, []byte(value), 0600); err != nil {
+			config.cleanup()
+			return nil, fmt.Errorf(
+				"unable to write %s for repository %s: %w",
+				key,
+				repoURL,
+				err,
+			)
+		}
+		*dest = filePath
+	}
+	return config, nil
+}
+
+func (config *repoAuthConfig) getterOptions() []helmgetter.Option {
+	var options []helmgetter.Option
+	if config.username != "" || config.password != "" {
+		options = append(options, helmgetter.WithBasicAuth(config.username, config.password))
+	}
+	if config.certFile != "" || config.keyFile != "" || config.caFile != "" {
+		options = append(
+			options,
+			helmgetter.WithTLSClientConfig(config.certFile, config.keyFile, config.caFile),
+		)
+	}
+	if config.insecureSkipTLSverify {
+		options = append(options, helmgetter.WithInsecureSkipVerifyTLS(true))
+	}
+	return options
+}
+
+// verifyChartProvenance downloads the .prov signature file published
+// alongside chartURL (using the same getter and auth options used to
+// fetch the chart itself) and verifies chartBytes against it using
+// keyringFile, mirroring how `helm pull --verify` validates chart
+// provenance.
+func verifyChartProvenance(
+	loader *helmRepoChartLoader,
+	getter helmgetter.Getter,
+	chartURL string,
+	chartBytes []byte,
+	getterOptions []helmgetter.Option,
+	keyringFile string,
+) error {
+	provURL := chartURL + ".prov"
+
+	parsedURL, urlErr := url.Parse(provURL)
+	if urlErr == nil {
+		if err := loader.rateLimiter.wait(loader.ctx, parsedURL.Host); err != nil {
+			return fmt.Errorf("rate limit wait for %s: %w", parsedURL.Host, err)
+		}
+	}
+
+	var provData *bytes.Buffer
+	err := withRetry(loader.ctx, loader.logger, loader.retry, "fetch chart provenance", func() error {
+		var err error
+		provData, err = getter.Get(provURL, getterOptions...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf(
+			"unable to download provenance file %s: %w",
+			provURL,
+			err,
+		)
+	}
+
+	// The provenance signature embeds the chart's own file name, so the
+	// temporary copy used to verify it must keep that same name rather
+	// than an arbitrary one.
+	verifyDir, err := os.MkdirTemp("", "chart-verify-")
+	if err != nil {
+		return fmt.Errorf(
+			"unable to create a temporary directory to verify chart provenance: %w",
+			err,
+		)
+	}
+	defer os.RemoveAll(verifyDir)
+
+	chartFilePath := path.Join(verifyDir, path.Base(chartURL))
+	if err := os.WriteFile(chartFilePath, chartBytes, 0600); err != nil {
+		return fmt.Errorf(
+			"unable to write chart to a temporary file to verify provenance: %w",
+			err,
+		)
+	}
+
+	provFilePath := chartFilePath + ".prov"
+	if err := os.WriteFile(provFilePath, provData.Bytes(), 0600); err != nil {
+		return fmt.Errorf("unable to write provenance file to verify chart: %w", err)
+	}
+
+	signatory, err := provenance.NewFromKeyring(keyringFile, "")
+	if err != nil {
+		return fmt.Errorf("unable to load provenance keyring %s: %w", keyringFile, err)
+	}
+	if _, err := signatory.Verify(chartFilePath, provFilePath); err != nil {
+		return fmt.Errorf(
+			"chart provenance verification failed for %s: %w",
+			chartURL,
+			err,
+		)
+	}
+	return nil
+}
 
 type helmRepoChartLoader struct {
 	loaderConfig
@@ -107,12 +312,23 @@ func (loader *helmRepoChartLoader) loadChartByURL(
 		)
 	}
 
+	authConfig, err := materializeRepoAuthConfig(loader.credentials, repoURL)
+	if err != nil {
+		return nil, err
+	}
+	defer authConfig.cleanup()
+
 	getters := helmgetter.All(&cli.EnvSettings{})
 	chartRepo, err := helmrepo.NewChartRepository(
 		&helmrepo.Entry{
-			Name: path.Join(repoPath, "repo"),
-			URL:  repoURL,
-			// TODO(vlad): Use chart repository options when provided.
+			Name:                  path.Join(repoPath, "repo"),
+			URL:                   repoURL,
+			Username:              authConfig.username,
+			Password:              authConfig.password,
+			CertFile:              authConfig.certFile,
+			KeyFile:               authConfig.keyFile,
+			CAFile:                authConfig.caFile,
+			InsecureSkipTLSverify: authConfig.insecureSkipTLSverify,
 		},
 		getters,
 	)
@@ -121,22 +337,46 @@ func (loader *helmRepoChartLoader) loadChartByURL(
 	}
 	chartRepo.CachePath = repoPath
 
-	indexFilePath, err := chartRepo.DownloadIndexFile()
-	if err != nil {
-		return nil, fmt.Errorf(
-			"unable to download index file for Helm repository %s: %w",
-			repoURL,
-			err,
-		)
+	// Serialize the index download by cache directory so that concurrent
+	// HelmRelease expansions targeting the same Helm repository share a
+	// single download instead of racing to populate repoPath.
+	repoHost := ""
+	if u, err := url.Parse(repoURL); err == nil {
+		repoHost = u.Host
 	}
-	repoIndex, err := helmrepo.LoadIndexFile(indexFilePath)
+
+	indexResult, release, err := loader.repoLock.Acquire(repoPath, func() (any, func(), error) {
+		if err := loader.rateLimiter.wait(loader.ctx, repoHost); err != nil {
+			return nil, nil, fmt.Errorf("rate limit wait for %s: %w", repoHost, err)
+		}
+		var indexFilePath string
+		err := withRetry(loader.ctx, loader.logger, loader.retry, "download chart index", func() error {
+			var err error
+			indexFilePath, err = chartRepo.DownloadIndexFile()
+			return err
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf(
+				"unable to download index file for Helm repository %s: %w",
+				repoURL,
+				err,
+			)
+		}
+		repoIndex, err := helmrepo.LoadIndexFile(indexFilePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf(
+				"unable to load index file for Helm repository %s: %w",
+				repoURL,
+				err,
+			)
+		}
+		return repoIndex, nil, nil
+	})
+	release()
 	if err != nil {
-		return nil, fmt.Errorf(
-			"unable to load index file for Helm repository %s: %w",
-			repoURL,
-			err,
-		)
+		return nil, err
 	}
+	repoIndex := indexResult.(*helmrepo.IndexFile)
 	chartRepo.IndexFile = repoIndex
 	version, err := repoIndex.Get(chartName, chartVersionSpec)
 	if err != nil {
@@ -151,17 +391,26 @@ func (loader *helmRepoChartLoader) loadChartByURL(
 
 	chartVersion := version.Version
 	chartKey := fmt.Sprintf("%s#%s#%s", repoURL, chartName, chartVersion)
-	if loader.chartCache != nil {
-		if chart, ok := loader.chartCache[chartKey]; ok {
-			loader.logger.
-				With(
-					"repoURL", repoURL,
-					"name", chartName,
-					"version", chartVersion,
-				).
-				Debug("Using chart from in-memory cache")
-			return chart, nil
-		}
+	if chart, ok := loader.chartCache.get(chartKey); ok {
+		loader.logger.
+			With(
+				"repoURL", repoURL,
+				"name", chartName,
+				"version", chartVersion,
+			).
+			Debug("Using chart from in-memory cache")
+		return chart, nil
+	}
+	if chart, ok := loader.diskChartCache.get(repoURL, chartName, chartVersion); ok {
+		loader.logger.
+			With(
+				"repoURL", repoURL,
+				"name", chartName,
+				"version", chartVersion,
+			).
+			Debug("Using chart from on-disk cache")
+		loader.chartCache.set(chartKey, chart)
+		return chart, nil
 	}
 
 	parsedURL, err := url.Parse(version.URLs[0])
@@ -183,9 +432,15 @@ func (loader *helmRepoChartLoader) loadChartByURL(
 		)
 	}
 
-	chartData, err := getter.Get(
-		parsedURL.String(),
-		[]helmgetter.Option{}...) // TODO(vlad): Set options if necessary.
+	if err := loader.rateLimiter.wait(loader.ctx, parsedURL.Host); err != nil {
+		return nil, fmt.Errorf("rate limit wait for %s: %w", parsedURL.Host, err)
+	}
+	var chartData *bytes.Buffer
+	err = withRetry(loader.ctx, loader.logger, loader.retry, "download chart", func() error {
+		var err error
+		chartData, err = getter.Get(parsedURL.String(), authConfig.getterOptions()...)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf(
 			"unable to download chart %s: %w",
@@ -193,8 +448,32 @@ func (loader *helmRepoChartLoader) loadChartByURL(
 			err,
 		)
 	}
+	chartBytes := chartData.Bytes()
+
+	if loader.verifyProvenance || authConfig.verifyProvenance {
+		keyringFile := authConfig.keyringFile
+		if keyringFile == "" {
+			keyringFile = loader.keyringFile
+		}
+		if keyringFile == "" {
+			return nil, fmt.Errorf(
+				"provenance verification requested for chart %s but no keyring is configured",
+				version.URLs[0],
+			)
+		}
+		if err := verifyChartProvenance(
+			loader,
+			getter,
+			version.URLs[0],
+			chartBytes,
+			authConfig.getterOptions(),
+			keyringFile,
+		); err != nil {
+			return nil, err
+		}
+	}
 
-	chart, err := helmloader.LoadArchive(chartData)
+	chart, err := helmloader.LoadArchive(bytes.NewReader(chartBytes))
 	if err != nil {
 		return nil, fmt.Errorf(
 			"unable to load chart %s/%s in %s: %w",
@@ -216,8 +495,11 @@ func (loader *helmRepoChartLoader) loadChartByURL(
 		)
 	}
 
-	if loader.chartCache != nil {
-		loader.chartCache[chartKey] = chart
+	loader.chartCache.set(chartKey, chart)
+	if err := loader.diskChartCache.set(repoURL, chartName, chartVersion, chartBytes); err != nil {
+		loader.logger.
+			With("repoURL", repoURL, "name", chartName, "version", chartVersion, "error", err).
+			Warn("Unable to store chart in on-disk cache")
 	}
 
 	loader.logger.
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// HookPolicy controls which Helm hook resources (objects annotated with
+// release.HookAnnotation) expandHelmRelease includes in its output. The
+// zero value (the empty string) behaves like HookPolicyIncludeAll,
+// rendering every hook alongside regular resources, the same as before
+// HookPolicy existed.
+type HookPolicy string
+
+const (
+	// HookPolicyIncludeAll renders every resource, hooks included.
+	HookPolicyIncludeAll HookPolicy = "IncludeAll"
+	// HookPolicySkipTests drops resources whose hook annotation includes
+	// "test", matching "helm template --skip-tests".
+	HookPolicySkipTests HookPolicy = "SkipTests"
+	// HookPolicySkipHooks drops every resource carrying a hook annotation,
+	// tests included.
+	HookPolicySkipHooks HookPolicy = "SkipHooks"
+	// HookPolicyHooksOnly keeps only resources carrying a hook annotation,
+	// dropping everything else.
+	HookPolicyHooksOnly HookPolicy = "HooksOnly"
+)
+
+// CRDPolicy controls whether and where the CustomResourceDefinitions
+// bundled in a chart's crds/ directory appear in expandHelmRelease's
+// output. Unlike templates, files there are installed by Helm verbatim
+// rather than rendered, so they never appear in engine.Render's output on
+// their own. The zero value (the empty string) behaves like CRDPolicySkip
+// and omits them, the same as before CRDPolicy existed.
+type CRDPolicy string
+
+const (
+	// CRDPolicySkip omits crds/ entirely.
+	CRDPolicySkip CRDPolicy = "Skip"
+	// CRDPolicyInclude emits crds/ alongside the chart's other resources,
+	// sorted among them as usual, matching "helm template --include-crds".
+	CRDPolicyInclude CRDPolicy = "Include"
+	// CRDPolicySeparate emits crds/ like CRDPolicyInclude, but ahead of
+	// every other resource in the final sorted output.
+	CRDPolicySeparate CRDPolicy = "Separate"
+)
+
+// hookAnnotationValues returns the comma-separated values of node's
+// release.HookAnnotation, or nil if node does not carry one.
+func hookAnnotationValues(node *yaml.RNode) []string {
+	raw, ok := node.GetAnnotations(release.HookAnnotation)[release.HookAnnotation]
+	if !ok || strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	values := strings.Split(raw, ",")
+	for i, value := range values {
+		values[i] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+func isTestHook(values []string) bool {
+	for _, value := range values {
+		if value == string(release.HookTest) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterHooks returns the subset of nodes that policy keeps.
+func filterHooks(nodes []*yaml.RNode, policy HookPolicy) []*yaml.RNode {
+	if policy == "" || policy == HookPolicyIncludeAll {
+		return nodes
+	}
+
+	filtered := make([]*yaml.RNode, 0, len(nodes))
+	for _, node := range nodes {
+		values := hookAnnotationValues(node)
+		isHook := len(values) > 0
+
+		switch policy {
+		case HookPolicySkipTests:
+			if isTestHook(values) {
+				continue
+			}
+		case HookPolicySkipHooks:
+			if isHook {
+				continue
+			}
+		case HookPolicyHooksOnly:
+			if !isHook {
+				continue
+			}
+		}
+		filtered = append(filtered, node)
+	}
+	return filtered
+}
+
+// crdNodesForChart parses the CRD manifests bundled in sourceChart's (and
+// its dependencies') crds/ directory into nodes, the way "helm template
+// --include-crds" surfaces them: verbatim, without passing through the
+// Helm template engine.
+func crdNodesForChart(sourceChart *chart.Chart) ([]*yaml.RNode, error) {
+	var result []*yaml.RNode
+	for _, crd := range sourceChart.CRDObjects() {
+		reader := kio.ByteReader{Reader: bytes.NewReader(crd.File.Data)}
+		nodes, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse CRD %s: %w", crd.Filename, err)
+		}
+		result = append(result, nodes...)
+	}
+	return result, nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+var _ = ginkgo.Describe("filterHooks", func() {
+	var g gomega.Gomega
+	var nodes []*yaml.RNode
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+		nodes = mustParseValuesNodes(g, strings.Join([]string{
+			"apiVersion: v1",
+			"kind: ConfigMap",
+			"metadata:",
+			"  name: plain",
+			"---",
+			"apiVersion: batch/v1",
+			"kind: Job",
+			"metadata:",
+			"  name: pre-install-hook",
+			"  annotations:",
+			"    helm.sh/hook: pre-install",
+			"---",
+			"apiVersion: v1",
+			"kind: Pod",
+			"metadata:",
+			"  name: test-hook",
+			"  annotations:",
+			"    helm.sh/hook: test",
+		}, "\n"))
+	})
+
+	names := func(nodes []*yaml.RNode) []string {
+		result := make([]string, len(nodes))
+		for i, node := range nodes {
+			result[i] = node.GetName()
+		}
+		return result
+	}
+
+	ginkgo.It("keeps every resource under HookPolicyIncludeAll", func() {
+		g.Expect(names(filterHooks(nodes, HookPolicyIncludeAll))).To(
+			gomega.ConsistOf("plain", "pre-install-hook", "test-hook"),
+		)
+	})
+
+	ginkgo.It("keeps every resource under the zero value", func() {
+		g.Expect(names(filterHooks(nodes, ""))).To(
+			gomega.ConsistOf("plain", "pre-install-hook", "test-hook"),
+		)
+	})
+
+	ginkgo.It("drops only test hooks under HookPolicySkipTests", func() {
+		g.Expect(names(filterHooks(nodes, HookPolicySkipTests))).To(
+			gomega.ConsistOf("plain", "pre-install-hook"),
+		)
+	})
+
+	ginkgo.It("drops every hook under HookPolicySkipHooks", func() {
+		g.Expect(names(filterHooks(nodes, HookPolicySkipHooks))).To(
+			gomega.ConsistOf("plain"),
+		)
+	})
+
+	ginkgo.It("keeps only hooks under HookPolicyHooksOnly", func() {
+		g.Expect(names(filterHooks(nodes, HookPolicyHooksOnly))).To(
+			gomega.ConsistOf("pre-install-hook", "test-hook"),
+		)
+	})
+})
+
+var _ = ginkgo.Describe("crdNodesForChart", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("parses manifests from the chart's crds/ directory, including dependencies", func() {
+		dependency := &chart.Chart{
+			Metadata: &chart.Metadata{Name: "dep"},
+			Files: []*chart.File{
+				{
+					Name: "crds/dep-crd.yaml",
+					Data: []byte(strings.Join([]string{
+						"apiVersion: apiextensions.k8s.io/v1",
+						"kind: CustomResourceDefinition",
+						"metadata:",
+						"  name: deps.example.com",
+					}, "\n")),
+				},
+			},
+		}
+		parent := &chart.Chart{
+			Metadata: &chart.Metadata{Name: "parent"},
+			Files: []*chart.File{
+				{
+					Name: "crds/parent-crd.yaml",
+					Data: []byte(strings.Join([]string{
+						"apiVersion: apiextensions.k8s.io/v1",
+						"kind: CustomResourceDefinition",
+						"metadata:",
+						"  name: parents.example.com",
+					}, "\n")),
+				},
+			},
+		}
+		parent.AddDependency(dependency)
+
+		nodes, err := crdNodesForChart(parent)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+
+		names := make([]string, len(nodes))
+		for i, node := range nodes {
+			names[i] = node.GetName()
+		}
+		g.Expect(names).To(gomega.ConsistOf("parents.example.com", "deps.example.com"))
+	})
+
+	ginkgo.It("returns nothing for a chart with no crds/ directory", func() {
+		nodes, err := crdNodesForChart(&chart.Chart{Metadata: &chart.Metadata{Name: "plain"}})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(nodes).To(gomega.BeEmpty())
+	})
+})
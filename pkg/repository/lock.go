@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"sync"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// chartCacheStore is a concurrency-safe in-memory cache of loaded charts
+// keyed by the loader-specific chart key (repo URL, chart name, version
+// and, for Git, the resolved reference). A nil *chartCacheStore disables
+// caching, mirroring the previous nil-map convention.
+type chartCacheStore struct {
+	mu    sync.Mutex
+	cache map[string]*chart.Chart
+}
+
+func newChartCacheStore() *chartCacheStore {
+	return &chartCacheStore{cache: map[string]*chart.Chart{}}
+}
+
+func (store *chartCacheStore) get(key string) (*chart.Chart, bool) {
+	if store == nil {
+		return nil, false
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	chart, ok := store.cache[key]
+	return chart, ok
+}
+
+func (store *chartCacheStore) set(key string, chart *chart.Chart) {
+	if store == nil {
+		return
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.cache[key] = chart
+}
+
+// repositoryLock serializes the expensive, side-effecting initialization
+// of a repository (a Git clone, a Helm index download) across concurrent
+// HelmRelease expansions that target the same repository, modeled after
+// Argo CD's reposerver lock: the first caller for a given key runs init
+// while the rest wait on a sync.Cond, and all callers then share init's
+// result. The cleanup func init returns runs once the last caller that
+// acquired the key releases it.
+type repositoryLock struct {
+	mu      sync.Mutex
+	entries map[string]*repoLockEntry
+}
+
+type repoLockEntry struct {
+	cond     *sync.Cond
+	ready    bool
+	result   any
+	err      error
+	cleanup  func()
+	refCount int
+}
+
+func newRepositoryLock() *repositoryLock {
+	return &repositoryLock{entries: map[string]*repoLockEntry{}}
+}
+
+// Acquire runs init exactly once for key among concurrent callers and
+// returns its result (or error) to all of them. The returned release func
+// must be called exactly once by every caller, including one for which
+// init itself failed; the cleanup func returned by init runs when the
+// last outstanding release for key happens.
+func (lock *repositoryLock) Acquire(
+	key string,
+	init func() (result any, cleanup func(), err error),
+) (result any, release func(), err error) {
+	if lock == nil {
+		result, cleanup, err := init()
+		return result, func() {
+			if cleanup != nil {
+				cleanup()
+			}
+		}, err
+	}
+
+	lock.mu.Lock()
+	entry, exists := lock.entries[key]
+	if !exists {
+		entry = &repoLockEntry{}
+		entry.cond = sync.NewCond(&lock.mu)
+		lock.entries[key] = entry
+	}
+	entry.refCount++
+	leader := !exists
+	for !leader && !entry.ready {
+		entry.cond.Wait()
+	}
+	lock.mu.Unlock()
+
+	if leader {
+		result, cleanup, err := init()
+		lock.mu.Lock()
+		entry.result = result
+		entry.cleanup = cleanup
+		entry.err = err
+		entry.ready = true
+		lock.mu.Unlock()
+		entry.cond.Broadcast()
+	}
+
+	release = func() {
+		lock.mu.Lock()
+		defer lock.mu.Unlock()
+		entry.refCount--
+		if entry.refCount == 0 {
+			delete(lock.entries, key)
+			if entry.cleanup != nil {
+				entry.cleanup()
+			}
+		}
+	}
+	return entry.result, release, entry.err
+}
@@ -1,7 +1,11 @@
 package repository
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"path"
 	"sort"
@@ -10,18 +14,48 @@ import (
 	"github.com/Masterminds/semver/v3"
 	sourcev1beta2 "github.com/fluxcd/source-controller/api/v1beta2"
 	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
 	"helm.sh/helm/v3/pkg/chart"
 	helmloader "helm.sh/helm/v3/pkg/chart/loader"
 	helmgetter "helm.sh/helm/v3/pkg/getter"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 
 	"github.com/fluxcd/pkg/oci/auth/aws"
+	"github.com/fluxcd/pkg/oci/auth/azure"
+	"github.com/fluxcd/pkg/oci/auth/gcp"
 	"github.com/fluxcd/pkg/version"
 	"helm.sh/helm/v3/pkg/registry"
 )
 
 var ociSchemePrefix string = fmt.Sprintf("%s://", registry.OCIScheme)
 
+// OCIPullerInterface abstracts pulling a chart tarball from an OCI
+// registry so that tests can inject a fake puller the way
+// GitClientInterface is faked by GitClientMock.
+type OCIPullerInterface interface {
+	Pull(chartRef string) (*bytes.Buffer, error)
+}
+
+type ociPullerFactoryFunc func(registryClient *registry.Client) (OCIPullerInterface, error)
+
+type helmOCIPuller struct {
+	getter *helmgetter.Getter
+}
+
+func (puller *helmOCIPuller) Pull(chartRef string) (*bytes.Buffer, error) {
+	return puller.getter.Get(chartRef)
+}
+
+func newHelmOCIPuller(registryClient *registry.Client) (OCIPullerInterface, error) {
+	getter, err := helmgetter.NewOCIGetter(
+		helmgetter.WithRegistryClient(registryClient),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &helmOCIPuller{getter: getter}, nil
+}
+
 type ociRepoChartLoader struct {
 	loaderConfig
 }
@@ -30,24 +64,248 @@ func newOciRepositoryLoader(config loaderConfig) repositoryLoader {
 	return &ociRepoChartLoader{loaderConfig: config}
 }
 
-func (loader *ociRepoChartLoader) awsLogin(registryHost string) (*authn.AuthConfig, error) {
-	authenticator, err := aws.NewClient().Login(loader.ctx, true, registryHost)
+// loginFromCredentials logs into the OCI registry using repoCreds, looked
+// up from the Credentials map by repository URL; see ociLoginCredentials
+// for the supported credential forms.
+func (loader *ociRepoChartLoader) loginFromCredentials(
+	registryClient *registry.Client,
+	parsedURL *url.URL,
+	repoCreds *RepositoryCreds,
+) (bool, error) {
+	if repoCreds == nil {
+		return false, nil
+	}
+
+	username, password, ok := ociLoginCredentials(repoCreds, parsedURL.Host)
+	if !ok {
+		return false, nil
+	}
+
+	if err := loader.rateLimiter.wait(loader.ctx, parsedURL.Host); err != nil {
+		return false, fmt.Errorf("rate limit wait for registry %s: %w", parsedURL.Host, err)
+	}
+	err := withRetry(loader.ctx, loader.logger, loader.retry, "registry login", func() error {
+		return registryClient.Login(
+			parsedURL.Host,
+			registry.LoginOptBasicAuth(username, password),
+		)
+	})
 	if err != nil {
-		return nil, fmt.Errorf(
-			"unable to log into repository %s: %w",
+		return false, fmt.Errorf(
+			"unable to log in to registry %s with configured credentials: %w",
+			parsedURL.Host,
+			err,
+		)
+	}
+	return true, nil
+}
+
+// ociLoginCredentials derives the username/password pair to log into the
+// OCI registry at registryHost with from repoCreds. It tries, in order: a
+// dockerconfigjson blob (the same format as a kubernetes.io/dockerconfigjson
+// Secret) keyed by registryHost; an explicit username/password pair; and a
+// bearer/identity token (OCIToken, or the legacy "token"/"identityToken"
+// map entries) presented as the password of an empty-username login, the
+// way Docker accepts one. ok is false when repoCreds carries nothing
+// usable for logging in.
+func ociLoginCredentials(repoCreds *RepositoryCreds, registryHost string) (username string, password string, ok bool) {
+	if dockerConfigJSON := repoCreds.Credentials["dockerconfigjson"]; dockerConfigJSON != "" {
+		if username, password, ok := credentialsFromDockerConfigJSON(dockerConfigJSON, registryHost); ok {
+			return username, password, true
+		}
+	}
+
+	username = repoCreds.Credentials["username"]
+	password = repoCreds.Credentials["password"]
+	if password == "" {
+		password = firstNonEmpty(
+			repoCreds.OCIToken,
+			repoCreds.Credentials["token"],
+			repoCreds.Credentials["identityToken"],
+		)
+	}
+	if password == "" {
+		return "", "", false
+	}
+	return username, password, true
+}
+
+// dockerConfigJSON is the subset of the kubernetes.io/dockerconfigjson /
+// ~/.docker/config.json schema needed to extract registry credentials.
+type dockerConfigJSON struct {
+	Auths map[string]struct {
+		Auth          string `json:"auth"`
+		Username      string `json:"username"`
+		Password      string `json:"password"`
+		IdentityToken string `json:"identitytoken"`
+	} `json:"auths"`
+}
+
+// credentialsFromDockerConfigJSON extracts the username/password to log
+// into registryHost with from raw, a dockerconfigjson blob. ok is false
+// when raw doesn't parse or has no entry for registryHost.
+func credentialsFromDockerConfigJSON(raw string, registryHost string) (username string, password string, ok bool) {
+	var config dockerConfigJSON
+	if err := json.Unmarshal([]byte(raw), &config); err != nil {
+		return "", "", false
+	}
+
+	entry, found := config.Auths[registryHost]
+	if !found {
+		return "", "", false
+	}
+	if entry.IdentityToken != "" {
+		return "", entry.IdentityToken, true
+	}
+	if entry.Username != "" || entry.Password != "" {
+		return entry.Username, entry.Password, true
+	}
+	if entry.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return "", "", false
+		}
+		user, pass, found := strings.Cut(string(decoded), ":")
+		if !found {
+			return "", "", false
+		}
+		return user, pass, true
+	}
+	return "", "", false
+}
+
+// registryClientOptions builds the registry.ClientOption set used to
+// construct the OCI registry client for repoCreds, enabling plain HTTP
+// (no TLS) when insecure_plain_http is set, for talking to local or
+// otherwise unencrypted registries in tests and air-gapped setups. The
+// client's HTTP transport is also wrapped so a retryable response
+// carrying a Retry-After header (e.g. Docker Hub rate limiting) surfaces
+// that delay to withRetry instead of losing it to the registry client's
+// own error handling.
+func registryClientOptions(repoCreds *RepositoryCreds) []registry.ClientOption {
+	options := []registry.ClientOption{
+		registry.ClientOptHTTPClient(&http.Client{Transport: newRetryAfterTransport(nil)}),
+	}
+	if repoCreds != nil && repoCreds.Credentials["insecure_plain_http"] == "true" {
+		options = append(options, registry.ClientOptPlainHTTP())
+	}
+	return options
+}
+
+// providerLogin logs registryClient into registryHost using the
+// cloud-provider workload identity selected by provider ("aws", "azure",
+// or "gcp"), mirroring how source-controller picks a provider from
+// HelmRepository.Spec.Provider / OCIRepository.Spec.Provider. It is a
+// no-op, returning loggedIn=false without error, for provider "" or
+// "generic", leaving the caller to fall back to ambient credentials.
+func (loader *ociRepoChartLoader) providerLogin(
+	registryClient *registry.Client,
+	provider string,
+	registryHost string,
+) (bool, error) {
+	var authenticator authn.Authenticator
+	var err error
+
+	switch provider {
+	case "aws":
+		authenticator, err = aws.NewClient().Login(loader.ctx, true, registryHost)
+	case "azure":
+		// ref is unused by azure.Client.Login; it accepts nil.
+		authenticator, err = azure.NewClient().Login(loader.ctx, true, registryHost, nil)
+	case "gcp":
+		// ref is unused by gcp.Client.Login; it accepts nil.
+		authenticator, err = gcp.NewClient().Login(loader.ctx, true, registryHost, nil)
+	case "", "generic":
+		return false, nil
+	default:
+		return false, fmt.Errorf("unknown OCI repository provider %q", provider)
+	}
+	if err != nil {
+		return false, fmt.Errorf(
+			"unable to log in to %s registry %s: %w",
+			provider,
 			registryHost,
 			err,
 		)
 	}
+
 	authConfig, err := authenticator.Authorization()
 	if err != nil {
-		return nil, fmt.Errorf(
-			"unable to log into repository %s: %w",
+		return false, fmt.Errorf(
+			"unable to log in to %s registry %s: %w",
+			provider,
+			registryHost,
+			err,
+		)
+	}
+	if err := loader.rateLimiter.wait(loader.ctx, registryHost); err != nil {
+		return false, fmt.Errorf("rate limit wait for registry %s: %w", registryHost, err)
+	}
+	err = withRetry(loader.ctx, loader.logger, loader.retry, "registry login", func() error {
+		return registryClient.Login(
+			registryHost,
+			registry.LoginOptBasicAuth(authConfig.Username, authConfig.Password),
+		)
+	})
+	if err != nil {
+		return false, fmt.Errorf(
+			"unable to log in to registry %s: %w",
 			registryHost,
 			err,
 		)
 	}
-	return authConfig, nil
+	return true, nil
+}
+
+// ambientLogin attempts to log registryClient into registryHost using
+// whatever credentials authn.DefaultKeychain can resolve ambiently,
+// chiefly the local ~/.docker/config.json. When none resolve, it leaves
+// registryClient unauthenticated so the subsequent pull is attempted
+// anonymously rather than failing outright.
+func (loader *ociRepoChartLoader) ambientLogin(
+	registryClient *registry.Client,
+	registryHost string,
+) error {
+	reg, err := name.NewRegistry(registryHost)
+	if err != nil {
+		return fmt.Errorf("unable to parse registry host %s: %w", registryHost, err)
+	}
+
+	authenticator, err := authn.DefaultKeychain.Resolve(reg)
+	if err != nil {
+		return fmt.Errorf(
+			"unable to resolve ambient credentials for registry %s: %w",
+			registryHost,
+			err,
+		)
+	}
+
+	authConfig, err := authenticator.Authorization()
+	if err != nil {
+		return fmt.Errorf(
+			"unable to resolve ambient credentials for registry %s: %w",
+			registryHost,
+			err,
+		)
+	}
+	if *authConfig == (authn.AuthConfig{}) {
+		return nil
+	}
+
+	password := firstNonEmpty(authConfig.Password, authConfig.IdentityToken, authConfig.RegistryToken)
+	if err := loader.rateLimiter.wait(loader.ctx, registryHost); err != nil {
+		return fmt.Errorf("rate limit wait for registry %s: %w", registryHost, err)
+	}
+	err = withRetry(loader.ctx, loader.logger, loader.retry, "registry login", func() error {
+		return registryClient.Login(
+			registryHost,
+			registry.LoginOptBasicAuth(authConfig.Username, password),
+		)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to log in to registry %s: %w", registryHost, err)
+	}
+	return nil
 }
 
 func getLatestMatchingVersion(
@@ -89,6 +347,14 @@ func getLatestMatchingVersion(
 	return matchingVersions[0].Original(), nil
 }
 
+// ociChartRepoPath returns the registry path (repository name) for
+// chartName within repoURL, with the oci:// scheme prefix stripped; the
+// registry client and puller both operate on bare registry/repository
+// paths rather than oci:// URLs.
+func ociChartRepoPath(repoURL string, chartName string) string {
+	return path.Join(strings.TrimPrefix(repoURL, ociSchemePrefix), chartName)
+}
+
 func (loader *ociRepoChartLoader) getChartVersion(
 	client *registry.Client,
 	repoURL string,
@@ -99,8 +365,22 @@ func (loader *ociRepoChartLoader) getChartVersion(
 		return chartVersionSpec, nil
 	}
 
-	chartRef := path.Join(strings.TrimPrefix(repoURL, ociSchemePrefix), chartName)
-	tags, err := client.Tags(chartRef)
+	chartRef := ociChartRepoPath(repoURL, chartName)
+
+	var tags []string
+	err := func() error {
+		parsedURL, err := url.Parse(repoURL)
+		if err == nil {
+			if err := loader.rateLimiter.wait(loader.ctx, parsedURL.Host); err != nil {
+				return err
+			}
+		}
+		return withRetry(loader.ctx, loader.logger, loader.retry, "list chart tags", func() error {
+			var err error
+			tags, err = client.Tags(chartRef)
+			return err
+		})
+	}()
 	if err != nil {
 		return "", fmt.Errorf("unable to fetch tags for %s: %w", chartRef, err)
 	}
@@ -159,26 +439,25 @@ func (loader *ociRepoChartLoader) loadRepositoryChart(
 		).
 		Debug("Loading chart from OCI Helm repository")
 
-	// TODO(vlad): Implement chart caching.
-	_, err = getCachePathForRepo(loader.cacheRoot, repoURL)
+	parsedURL, err := url.Parse(repoURL)
 	if err != nil {
 		return nil, fmt.Errorf(
-			"unable to get cache path for Helm repository %s: %w",
+			"unable to parse repository URL %s: %w",
 			repoURL,
 			err,
 		)
 	}
 
-	parsedURL, err := url.Parse(repoURL)
+	repoCreds, err := loader.credentials.FindForRepo(parsedURL)
 	if err != nil {
 		return nil, fmt.Errorf(
-			"unable to parse repository URL %s: %w",
+			"unable to find credentials for repository %s: %w",
 			repoURL,
 			err,
 		)
 	}
 
-	registryClient, err := registry.NewClient()
+	registryClient, err := registry.NewClient(registryClientOptions(repoCreds)...)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"unable to create registry client: %w",
@@ -186,25 +465,25 @@ func (loader *ociRepoChartLoader) loadRepositoryChart(
 		)
 	}
 
-	authConfig, err := loader.awsLogin(parsedURL.Host)
-	if err != nil {
-		return nil, fmt.Errorf(
-			"unable to log in to AWS registry %s: %w",
-			parsedURL.Host,
-			err,
-		)
+	var providerName string
+	if repo != nil {
+		providerName = repo.Spec.Provider
 	}
 
-	err = registryClient.Login(
-		parsedURL.Host,
-		registry.LoginOptBasicAuth(authConfig.Username, authConfig.Password),
-	)
+	loggedIn, err := loader.loginFromCredentials(registryClient, parsedURL, repoCreds)
 	if err != nil {
-		return nil, fmt.Errorf(
-			"unable to log in to registry %s: %w",
-			parsedURL.Host,
-			err,
-		)
+		return nil, err
+	}
+	if !loggedIn {
+		loggedIn, err = loader.providerLogin(registryClient, providerName, parsedURL.Host)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !loggedIn {
+		if err := loader.ambientLogin(registryClient, parsedURL.Host); err != nil {
+			return nil, err
+		}
 	}
 
 	chartVersion, err := loader.getChartVersion(
@@ -224,37 +503,52 @@ func (loader *ociRepoChartLoader) loadRepositoryChart(
 	}
 
 	chartKey := fmt.Sprintf("%s#%s#%s", repoURL, chartName, chartVersion)
-	if loader.chartCache != nil {
-		if chart, ok := loader.chartCache[chartKey]; ok {
-			loader.logger.
-				With(
-					"repoURL", repoURL,
-					"name", chartName,
-					"version", chartVersion,
-				).
-				Debug("Using chart from in-memory cache")
-			return chart, nil
-		}
+	if chart, ok := loader.chartCache.get(chartKey); ok {
+		loader.logger.
+			With(
+				"repoURL", repoURL,
+				"name", chartName,
+				"version", chartVersion,
+			).
+			Debug("Using chart from in-memory cache")
+		return chart, nil
+	}
+	if chart, ok := loader.diskChartCache.get(repoURL, chartName, chartVersion); ok {
+		loader.logger.
+			With(
+				"repoURL", repoURL,
+				"name", chartName,
+				"version", chartVersion,
+			).
+			Debug("Using chart from on-disk cache")
+		loader.chartCache.set(chartKey, chart)
+		return chart, nil
 	}
 
-	getter, err := helmgetter.NewOCIGetter(
-		helmgetter.WithRegistryClient(registryClient),
-	)
+	pullerFactory := loader.ociPullerFactory
+	if pullerFactory == nil {
+		pullerFactory = newHelmOCIPuller
+	}
+	puller, err := pullerFactory(registryClient)
 	if err != nil {
 		return nil, fmt.Errorf(
-			"unable to create Helm getter for %s: %w",
+			"unable to create OCI puller for %s: %w",
 			repoURL,
 			err,
 		)
 	}
 
-	chartRef := fmt.Sprintf(
-		"%s:%s",
-		path.Join(strings.TrimPrefix(repoURL, ociSchemePrefix), chartName),
-		chartVersion,
-	)
+	chartRef := fmt.Sprintf("%s:%s", ociChartRepoPath(repoURL, chartName), chartVersion)
 
-	chartData, err := getter.Get(chartRef)
+	if err := loader.rateLimiter.wait(loader.ctx, parsedURL.Host); err != nil {
+		return nil, fmt.Errorf("rate limit wait for registry %s: %w", parsedURL.Host, err)
+	}
+	var chartData *bytes.Buffer
+	err = withRetry(loader.ctx, loader.logger, loader.retry, "pull chart", func() error {
+		var err error
+		chartData, err = puller.Pull(chartRef)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf(
 			"unable to download chart %s for version constraint %s: %w",
@@ -264,7 +558,12 @@ func (loader *ociRepoChartLoader) loadRepositoryChart(
 		)
 	}
 
-	chart, err := helmloader.LoadArchive(chartData)
+	if err := loader.verifyChartSignature(chartRef, repoCreds, parsedURL.Host); err != nil {
+		return nil, err
+	}
+
+	chartBytes := chartData.Bytes()
+	chart, err := helmloader.LoadArchive(bytes.NewReader(chartBytes))
 	if err != nil {
 		return nil, fmt.Errorf(
 			"unable to load chart %s/%s in %s: %w",
@@ -286,8 +585,11 @@ func (loader *ociRepoChartLoader) loadRepositoryChart(
 		)
 	}
 
-	if loader.chartCache != nil {
-		loader.chartCache[chartKey] = chart
+	loader.chartCache.set(chartKey, chart)
+	if err := loader.diskChartCache.set(repoURL, chartName, chartVersion, chartBytes); err != nil {
+		loader.logger.
+			With("repoURL", repoURL, "name", chartName, "version", chartVersion, "error", err).
+			Warn("Unable to store chart in on-disk cache")
 	}
 
 	loader.logger.
@@ -299,3 +601,45 @@ func (loader *ociRepoChartLoader) loadRepositoryChart(
 		Debug("Finished loading chart")
 	return chart, nil
 }
+
+// verifyChartSignature checks chartRef's cosign signature against
+// loader.verification, the default verification policy, overridden by
+// repoCreds.VerificationPublicKeys when set. It is a no-op when neither
+// applies. registryHost resolves the digest and fetches signatures using
+// the same credentials and plain-HTTP setting the chart itself was
+// pulled with, via chartRegistryOptionsFromCreds.
+func (loader *ociRepoChartLoader) verifyChartSignature(
+	chartRef string,
+	repoCreds *RepositoryCreds,
+	registryHost string,
+) error {
+	config := loader.verification
+	if repoCreds != nil && len(repoCreds.VerificationPublicKeys) > 0 {
+		config.PublicKeys = repoCreds.VerificationPublicKeys
+		if config.Provider == "" {
+			config.Provider = ChartVerificationProviderCosign
+		}
+	}
+	registryOpts := chartRegistryOptionsFromCreds(repoCreds, registryHost)
+	return loader.signatureVerifier.verify(loader.ctx, chartRef, config, registryOpts)
+}
+
+// chartRegistryOptionsFromCreds builds the chartRegistryOptions signature
+// verification should use to reach registryHost, mirroring the same
+// insecure_plain_http check as registryClientOptions and the same
+// credential precedence as ociLoginCredentials.
+func chartRegistryOptionsFromCreds(repoCreds *RepositoryCreds, registryHost string) chartRegistryOptions {
+	opts := chartRegistryOptions{
+		insecure: repoCreds != nil && repoCreds.Credentials["insecure_plain_http"] == "true",
+	}
+	if repoCreds == nil {
+		return opts
+	}
+	if username, password, ok := ociLoginCredentials(repoCreds, registryHost); ok {
+		opts.authenticator = authn.FromConfig(authn.AuthConfig{
+			Username: username,
+			Password: password,
+		})
+	}
+	return opts
+}
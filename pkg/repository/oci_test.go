@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("OCI chart repository path handling", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("strips the oci:// scheme before building the registry path", func() {
+		g.Expect(ociChartRepoPath("oci://registry.example.com/charts", "test-chart")).To(
+			gomega.Equal("registry.example.com/charts/test-chart"),
+		)
+	})
+
+	ginkgo.It("leaves a bare registry host untouched", func() {
+		g.Expect(ociChartRepoPath("registry.example.com/charts", "test-chart")).To(
+			gomega.Equal("registry.example.com/charts/test-chart"),
+		)
+	})
+})
+
+var _ = ginkgo.Describe("OCI registry login credential resolution", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("prefers an explicit username/password pair", func() {
+		username, password, ok := ociLoginCredentials(&RepositoryCreds{
+			Credentials: map[string]string{
+				"username": "dummy-user",
+				"password": "dummy-password",
+			},
+			OCIToken: "dummy-bearer-token",
+		}, "registry.example.com")
+		g.Expect(ok).To(gomega.BeTrue())
+		g.Expect(username).To(gomega.Equal("dummy-user"))
+		g.Expect(password).To(gomega.Equal("dummy-password"))
+	})
+
+	ginkgo.It("logs in with an empty username when only a bearer token is configured", func() {
+		username, password, ok := ociLoginCredentials(&RepositoryCreds{
+			OCIToken: "dummy-bearer-token",
+		}, "registry.example.com")
+		g.Expect(ok).To(gomega.BeTrue())
+		g.Expect(username).To(gomega.Equal(""))
+		g.Expect(password).To(gomega.Equal("dummy-bearer-token"))
+	})
+
+	ginkgo.It("falls back to the legacy token map entry", func() {
+		username, password, ok := ociLoginCredentials(&RepositoryCreds{
+			Credentials: map[string]string{"token": "dummy-legacy-token"},
+		}, "registry.example.com")
+		g.Expect(ok).To(gomega.BeTrue())
+		g.Expect(username).To(gomega.Equal(""))
+		g.Expect(password).To(gomega.Equal("dummy-legacy-token"))
+	})
+
+	ginkgo.It("falls back to the identityToken map entry", func() {
+		username, password, ok := ociLoginCredentials(&RepositoryCreds{
+			Credentials: map[string]string{"identityToken": "dummy-identity-token"},
+		}, "registry.example.com")
+		g.Expect(ok).To(gomega.BeTrue())
+		g.Expect(username).To(gomega.Equal(""))
+		g.Expect(password).To(gomega.Equal("dummy-identity-token"))
+	})
+
+	ginkgo.It("reports no usable credentials when nothing is configured", func() {
+		_, _, ok := ociLoginCredentials(&RepositoryCreds{}, "registry.example.com")
+		g.Expect(ok).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("resolves a username/password pair from a dockerconfigjson blob", func() {
+		username, password, ok := ociLoginCredentials(&RepositoryCreds{
+			Credentials: map[string]string{
+				"dockerconfigjson": `{"auths":{"registry.example.com":{"username":"dummy-user","password":"dummy-password"}}}`,
+			},
+		}, "registry.example.com")
+		g.Expect(ok).To(gomega.BeTrue())
+		g.Expect(username).To(gomega.Equal("dummy-user"))
+		g.Expect(password).To(gomega.Equal("dummy-password"))
+	})
+
+	ginkgo.It("decodes the base64 auth field of a dockerconfigjson blob", func() {
+		username, password, ok := ociLoginCredentials(&RepositoryCreds{
+			Credentials: map[string]string{
+				// base64("dummy-user:dummy-password")
+				"dockerconfigjson": `{"auths":{"registry.example.com":{"auth":"ZHVtbXktdXNlcjpkdW1teS1wYXNzd29yZA=="}}}`,
+			},
+		}, "registry.example.com")
+		g.Expect(ok).To(gomega.BeTrue())
+		g.Expect(username).To(gomega.Equal("dummy-user"))
+		g.Expect(password).To(gomega.Equal("dummy-password"))
+	})
+
+	ginkgo.It("ignores a dockerconfigjson blob with no entry for the target registry", func() {
+		username, password, ok := ociLoginCredentials(&RepositoryCreds{
+			Credentials: map[string]string{
+				"dockerconfigjson": `{"auths":{"other.example.com":{"username":"dummy-user","password":"dummy-password"}}}`,
+				"password":         "fallback-password",
+			},
+		}, "registry.example.com")
+		g.Expect(ok).To(gomega.BeTrue())
+		g.Expect(username).To(gomega.Equal(""))
+		g.Expect(password).To(gomega.Equal("fallback-password"))
+	})
+})
+
+var _ = ginkgo.Describe("OCI registry provider login dispatch", func() {
+	var g gomega.Gomega
+	var loader *ociRepoChartLoader
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+		loader = &ociRepoChartLoader{loaderConfig: loaderConfig{ctx: context.Background()}}
+	})
+
+	ginkgo.It("is a no-op for an empty provider, leaving the caller to fall back to ambient credentials", func() {
+		loggedIn, err := loader.providerLogin(nil, "", "registry.example.com")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(loggedIn).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("is a no-op for the generic provider", func() {
+		loggedIn, err := loader.providerLogin(nil, "generic", "registry.example.com")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(loggedIn).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("rejects an unknown provider", func() {
+		_, err := loader.providerLogin(nil, "bogus", "registry.example.com")
+		g.Expect(err).To(gomega.HaveOccurred())
+	})
+})
+
+var _ = ginkgo.Describe("OCI registry client options", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("requests plain HTTP when insecure_plain_http is set", func() {
+		options := registryClientOptions(&RepositoryCreds{
+			Credentials: map[string]string{"insecure_plain_http": "true"},
+		})
+		g.Expect(options).To(gomega.HaveLen(2))
+	})
+
+	ginkgo.It("always installs the Retry-After-aware HTTP client, defaulting to TLS", func() {
+		g.Expect(registryClientOptions(nil)).To(gomega.HaveLen(1))
+		g.Expect(registryClientOptions(&RepositoryCreds{})).To(gomega.HaveLen(1))
+	})
+})
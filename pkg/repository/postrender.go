@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/fluxcd/pkg/apis/kustomize"
+	"sigs.k8s.io/kustomize/api/filters/imagetag"
+	"sigs.k8s.io/kustomize/api/filters/patchjson6902"
+	"sigs.k8s.io/kustomize/api/filters/patchstrategicmerge"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+	"sigs.k8s.io/kustomize/kyaml/yaml/merge2"
+
+	helmv2beta2 "github.com/fluxcd/helm-controller/api/v2beta2"
+)
+
+// PostRenderer transforms the manifests expandHelmRelease has rendered for
+// a single HelmRelease, the same role "helm template --post-renderer"
+// plays for the Helm CLI. It runs after the namespace.Filter step and
+// before the final cross-release sort inside releaseRepoRenderer.Filter.
+type PostRenderer interface {
+	Run(manifests []*yaml.RNode) ([]*yaml.RNode, error)
+}
+
+// ExecPostRenderer pipes rendered YAML through an external binary and
+// parses back whatever it writes to stdout, the way "helm template
+// --post-renderer <path>" invokes a post-rendering plugin.
+type ExecPostRenderer struct {
+	ctx  context.Context
+	path string
+	args []string
+}
+
+// NewExecPostRenderer returns a PostRenderer that runs path (with args)
+// once per HelmRelease, feeding it the rendered manifests on stdin as YAML
+// and reading the replacement manifests back from its stdout.
+func NewExecPostRenderer(ctx context.Context, path string, args ...string) *ExecPostRenderer {
+	return &ExecPostRenderer{ctx: ctx, path: path, args: args}
+}
+
+func (renderer *ExecPostRenderer) Run(manifests []*yaml.RNode) ([]*yaml.RNode, error) {
+	var input bytes.Buffer
+	writer := kio.ByteWriter{Writer: &input}
+	if err := writer.Write(manifests); err != nil {
+		return nil, fmt.Errorf("unable to encode manifests for post-renderer %s: %w", renderer.path, err)
+	}
+
+	cmd := exec.CommandContext(renderer.ctx, renderer.path, renderer.args...)
+	cmd.Stdin = &input
+	var output, stderr bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf(
+			"post-renderer %s failed: %w (stderr: %s)",
+			renderer.path,
+			err,
+			strings.TrimSpace(stderr.String()),
+		)
+	}
+
+	reader := kio.ByteReader{Reader: &output}
+	nodes, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse output of post-renderer %s: %w", renderer.path, err)
+	}
+	return nodes, nil
+}
+
+// KustomizePostRenderer applies an inline Kustomize overlay the way Flux's
+// HelmRelease spec.postRenderers[].kustomize does: Patches are applied in
+// list order, each to the resources its Target selector matches (or, when
+// Target is omitted, to the single resource whose apiVersion/kind/name/
+// namespace matches the patch document's own), followed by Images, which
+// rewrite container image references across every resource.
+//
+// The deprecated PatchesStrategicMerge and PatchesJSON6902 fields are not
+// supported; Flux itself recommends Patches in their place.
+type KustomizePostRenderer struct {
+	spec *helmv2beta2.Kustomize
+}
+
+// NewKustomizePostRenderer returns a PostRenderer applying spec.
+func NewKustomizePostRenderer(spec *helmv2beta2.Kustomize) *KustomizePostRenderer {
+	return &KustomizePostRenderer{spec: spec}
+}
+
+func (renderer *KustomizePostRenderer) Run(manifests []*yaml.RNode) ([]*yaml.RNode, error) {
+	if renderer.spec == nil {
+		return manifests, nil
+	}
+
+	result := manifests
+	for _, patch := range renderer.spec.Patches {
+		var err error
+		result, err = applyKustomizePatch(result, patch)
+		if err != nil {
+			return nil, fmt.Errorf("unable to apply Kustomize patch: %w", err)
+		}
+	}
+
+	for _, image := range renderer.spec.Images {
+		filter := imagetag.Filter{
+			ImageTag: types.Image{
+				Name:    image.Name,
+				NewName: image.NewName,
+				NewTag:  image.NewTag,
+				Digest:  image.Digest,
+			},
+			FsSlice: defaultImageFieldSpecs,
+		}
+		var err error
+		result, err = filter.Filter(result)
+		if err != nil {
+			return nil, fmt.Errorf("unable to apply Kustomize image %s: %w", image.Name, err)
+		}
+	}
+
+	return result, nil
+}
+
+// defaultImageFieldSpecs names the container image fields Kustomize's own
+// image transformer rewrites by default, for the common built-in workload
+// kinds (Pod, and anything with a Pod template, including CronJob's nested
+// one).
+var defaultImageFieldSpecs = types.FsSlice{
+	{Path: "spec/containers[]/image"},
+	{Path: "spec/initContainers[]/image"},
+	{Path: "spec/template/spec/containers[]/image"},
+	{Path: "spec/template/spec/initContainers[]/image"},
+	{Path: "spec/jobTemplate/spec/template/spec/containers[]/image"},
+	{Path: "spec/jobTemplate/spec/template/spec/initContainers[]/image"},
+}
+
+// applyKustomizePatch applies a single Kustomize patch (strategic merge or
+// JSON6902, auto-detected from its content the way Kustomize's "patches"
+// field does) to whichever of nodes patch.Target selects.
+func applyKustomizePatch(nodes []*yaml.RNode, patch kustomize.Patch) ([]*yaml.RNode, error) {
+	patchNode, err := yaml.Parse(patch.Patch)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse patch: %w", err)
+	}
+	isJSON6902 := patchNode.YNode().Kind == yaml.SequenceNode
+
+	if patch.Target == nil {
+		if isJSON6902 {
+			return nil, fmt.Errorf("a target is required for a JSON6902 patch")
+		}
+		return applyUntargetedStrategicMergePatch(nodes, patchNode)
+	}
+
+	var result []*yaml.RNode
+	for _, node := range nodes {
+		matches, err := kustomizeSelectorMatches(node, patch.Target)
+		if err != nil {
+			return nil, err
+		}
+		if !matches {
+			result = append(result, node)
+			continue
+		}
+
+		if isJSON6902 {
+			patched, err := (patchjson6902.Filter{Patch: patch.Patch}).Filter([]*yaml.RNode{node})
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, patched...)
+			continue
+		}
+
+		merged, err := merge2.Merge(patchNode, node, yaml.MergeOptions{
+			ListIncreaseDirection: yaml.MergeOptionsListPrepend,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if merged != nil {
+			result = append(result, merged)
+		}
+	}
+	return result, nil
+}
+
+// applyUntargetedStrategicMergePatch merges patchNode into whichever of
+// nodes has the same apiVersion, kind, name and namespace as patchNode
+// itself, the way a Kustomize "patches" entry with no target behaves.
+func applyUntargetedStrategicMergePatch(nodes []*yaml.RNode, patchNode *yaml.RNode) ([]*yaml.RNode, error) {
+	var result []*yaml.RNode
+	matched := false
+	for _, node := range nodes {
+		if node.GetApiVersion() != patchNode.GetApiVersion() ||
+			node.GetKind() != patchNode.GetKind() ||
+			node.GetName() != patchNode.GetName() ||
+			node.GetNamespace() != patchNode.GetNamespace() {
+			result = append(result, node)
+			continue
+		}
+		matched = true
+		merged, err := merge2.Merge(patchNode, node, yaml.MergeOptions{
+			ListIncreaseDirection: yaml.MergeOptionsListPrepend,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if merged != nil {
+			result = append(result, merged)
+		}
+	}
+	if !matched {
+		return nil, fmt.Errorf(
+			"no resource matches patch for %s %s/%s",
+			patchNode.GetKind(),
+			patchNode.GetNamespace(),
+			patchNode.GetName(),
+		)
+	}
+	return result, nil
+}
+
+func kustomizeSelectorMatches(node *yaml.RNode, selector *kustomize.Selector) (bool, error) {
+	if selector.Kind != "" && node.GetKind() != selector.Kind {
+		return false, nil
+	}
+	if selector.Namespace != "" && node.GetNamespace() != selector.Namespace {
+		return false, nil
+	}
+	if selector.Name != "" && node.GetName() != selector.Name {
+		return false, nil
+	}
+	if selector.Group != "" || selector.Version != "" {
+		group, version := splitApiVersion(node.GetApiVersion())
+		if selector.Group != "" && group != selector.Group {
+			return false, nil
+		}
+		if selector.Version != "" && version != selector.Version {
+			return false, nil
+		}
+	}
+	if selector.AnnotationSelector != "" {
+		ok, err := node.MatchesAnnotationSelector(selector.AnnotationSelector)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	if selector.LabelSelector != "" {
+		ok, err := node.MatchesLabelSelector(selector.LabelSelector)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func splitApiVersion(apiVersion string) (group, version string) {
+	if idx := strings.LastIndex(apiVersion, "/"); idx >= 0 {
+		return apiVersion[:idx], apiVersion[idx+1:]
+	}
+	return "", apiVersion
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fluxcd/pkg/apis/kustomize"
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("KustomizePostRenderer", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("returns the manifests unchanged for a nil spec", func() {
+		nodes := mustParseValuesNodes(g, strings.Join([]string{
+			"apiVersion: v1",
+			"kind: ConfigMap",
+			"metadata:",
+			"  name: a",
+		}, "\n"))
+
+		result, err := NewKustomizePostRenderer(nil).Run(nodes)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(result).To(gomega.Equal(nodes))
+	})
+
+	ginkgo.It("applies a strategic merge patch to the resource it targets", func() {
+		nodes := mustParseValuesNodes(g, strings.Join([]string{
+			"apiVersion: apps/v1",
+			"kind: Deployment",
+			"metadata:",
+			"  name: app",
+			"  namespace: testns",
+			"spec:",
+			"  replicas: 1",
+			"---",
+			"apiVersion: v1",
+			"kind: ConfigMap",
+			"metadata:",
+			"  name: other",
+			"  namespace: testns",
+		}, "\n"))
+
+		renderer := NewKustomizePostRenderer(&kustomize.Kustomize{
+			Patches: []kustomize.Patch{{
+				Patch: strings.Join([]string{
+					"spec:",
+					"  replicas: 3",
+				}, "\n"),
+				Target: &kustomize.Selector{Kind: "Deployment", Name: "app"},
+			}},
+		})
+
+		result, err := renderer.Run(nodes)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(result).To(gomega.HaveLen(2))
+
+		replicas, err := result[0].GetFieldValue("spec.replicas")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(replicas).To(gomega.Equal(3))
+	})
+
+	ginkgo.It("matches an untargeted patch against its own apiVersion/kind/name/namespace", func() {
+		nodes := mustParseValuesNodes(g, strings.Join([]string{
+			"apiVersion: apps/v1",
+			"kind: Deployment",
+			"metadata:",
+			"  name: app",
+			"  namespace: testns",
+			"spec:",
+			"  replicas: 1",
+		}, "\n"))
+
+		renderer := NewKustomizePostRenderer(&kustomize.Kustomize{
+			Patches: []kustomize.Patch{{
+				Patch: strings.Join([]string{
+					"apiVersion: apps/v1",
+					"kind: Deployment",
+					"metadata:",
+					"  name: app",
+					"  namespace: testns",
+					"spec:",
+					"  replicas: 5",
+				}, "\n"),
+			}},
+		})
+
+		result, err := renderer.Run(nodes)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(result).To(gomega.HaveLen(1))
+
+		replicas, err := result[0].GetFieldValue("spec.replicas")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(replicas).To(gomega.Equal(5))
+	})
+
+	ginkgo.It("fails an untargeted patch that matches nothing", func() {
+		renderer := NewKustomizePostRenderer(&kustomize.Kustomize{
+			Patches: []kustomize.Patch{{
+				Patch: strings.Join([]string{
+					"apiVersion: apps/v1",
+					"kind: Deployment",
+					"metadata:",
+					"  name: missing",
+					"spec:",
+					"  replicas: 5",
+				}, "\n"),
+			}},
+		})
+
+		_, err := renderer.Run(nil)
+		g.Expect(err).To(gomega.HaveOccurred())
+		g.Expect(err.Error()).To(gomega.ContainSubstring("no resource matches"))
+	})
+
+	ginkgo.It("applies a JSON6902 patch to the resource its target selects", func() {
+		nodes := mustParseValuesNodes(g, strings.Join([]string{
+			"apiVersion: apps/v1",
+			"kind: Deployment",
+			"metadata:",
+			"  name: app",
+			"spec:",
+			"  replicas: 1",
+		}, "\n"))
+
+		renderer := NewKustomizePostRenderer(&kustomize.Kustomize{
+			Patches: []kustomize.Patch{{
+				Patch: `[{"op": "replace", "path": "/spec/replicas", "value": 7}]`,
+				Target: &kustomize.Selector{
+					Kind: "Deployment",
+					Name: "app",
+				},
+			}},
+		})
+
+		result, err := renderer.Run(nodes)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		replicas, err := result[0].GetFieldValue("spec.replicas")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(replicas).To(gomega.Equal(7))
+	})
+
+	ginkgo.It("rewrites matching container images", func() {
+		nodes := mustParseValuesNodes(g, strings.Join([]string{
+			"apiVersion: apps/v1",
+			"kind: Deployment",
+			"metadata:",
+			"  name: app",
+			"spec:",
+			"  template:",
+			"    spec:",
+			"      containers:",
+			"      - name: main",
+			"        image: example.com/app:1.0",
+		}, "\n"))
+
+		renderer := NewKustomizePostRenderer(&kustomize.Kustomize{
+			Images: []kustomize.Image{{
+				Name:   "example.com/app",
+				NewTag: "2.0",
+			}},
+		})
+
+		result, err := renderer.Run(nodes)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		image, err := result[0].GetFieldValue("spec.template.spec.containers.0.image")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(image).To(gomega.Equal("example.com/app:2.0"))
+	})
+})
+
+var _ = ginkgo.Describe("ExecPostRenderer", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("parses whatever the external binary writes to stdout", func() {
+		nodes := mustParseValuesNodes(g, strings.Join([]string{
+			"apiVersion: v1",
+			"kind: ConfigMap",
+			"metadata:",
+			"  name: a",
+		}, "\n"))
+
+		renderer := NewExecPostRenderer(context.Background(), "cat")
+		result, err := renderer.Run(nodes)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(result).To(gomega.HaveLen(1))
+		g.Expect(result[0].GetName()).To(gomega.Equal("a"))
+	})
+
+	ginkgo.It("wraps the error from a failing binary", func() {
+		renderer := NewExecPostRenderer(context.Background(), "false")
+		_, err := renderer.Run(nil)
+		g.Expect(err).To(gomega.HaveOccurred())
+	})
+})
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	releasepkg "helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// RenderMode selects the chartutil.ReleaseOptions expandHelmRelease renders
+// a HelmRelease's chart with, mirroring whether "helm install" or "helm
+// upgrade" is the next action the helm-controller would actually perform.
+type RenderMode string
+
+const (
+	// RenderModeInstall always renders as a first install: Revision 1,
+	// IsInstall true, IsUpgrade false. The zero value (the empty string)
+	// behaves the same, matching the expander's behavior before RenderMode
+	// was configurable.
+	RenderModeInstall RenderMode = "Install"
+
+	// RenderModeUpgrade always renders as an upgrade. The previous
+	// revision is looked up the same way RenderModeAuto does; if none is
+	// found in the input stream, revision 2 is assumed.
+	RenderModeUpgrade RenderMode = "Upgrade"
+
+	// RenderModeAuto renders as an upgrade from the previous revision
+	// found in the input stream, or as a first install when none is
+	// found.
+	RenderModeAuto RenderMode = "Auto"
+)
+
+// helmReleaseSecretType is the Secret type Helm's Secrets storage driver
+// stamps onto every release revision it stores; see
+// helm.sh/helm/v3/pkg/storage/driver.Secrets.
+const helmReleaseSecretType = "helm.sh/release.v1"
+
+// releaseOptionsFor resolves Revision/IsInstall/IsUpgrade for release
+// according to mode, consulting nodes for a prior release storage Secret
+// (see findPreviousRevision) when mode requires it.
+func releaseOptionsFor(
+	mode RenderMode,
+	releaseName string,
+	storageNamespace string,
+	nodes []*yaml.RNode,
+) (revision int, isInstall bool, isUpgrade bool, err error) {
+	if mode != RenderModeUpgrade && mode != RenderModeAuto {
+		return 1, true, false, nil
+	}
+
+	prevRevision, found, err := findPreviousRevision(nodes, releaseName, storageNamespace)
+	if err != nil {
+		return 0, false, false, err
+	}
+	if found {
+		return prevRevision + 1, false, true, nil
+	}
+	if mode == RenderModeUpgrade {
+		return 2, false, true, nil
+	}
+	return 1, true, false, nil
+}
+
+// findPreviousRevision scans nodes for the helm.sh/release.v1 Secret (see
+// helm.sh/helm/v3/pkg/storage/driver.Secrets) holding the highest-numbered
+// revision of the release named releaseName in storageNamespace, and
+// returns that revision number. The second result is false when no such
+// Secret is present in nodes.
+func findPreviousRevision(
+	nodes []*yaml.RNode,
+	releaseName string,
+	storageNamespace string,
+) (int, bool, error) {
+	found := false
+	latest := 0
+
+	for _, node := range nodes {
+		if node.GetApiVersion() != "v1" ||
+			node.GetKind() != "Secret" ||
+			node.GetNamespace() != storageNamespace {
+			continue
+		}
+
+		var secret corev1.Secret
+		if err := decodeToObject(node, &secret); err != nil {
+			return 0, false, fmt.Errorf(
+				"unable to decode Secret %s/%s: %w",
+				node.GetNamespace(),
+				node.GetName(),
+				err,
+			)
+		}
+		if secret.Type != helmReleaseSecretType {
+			continue
+		}
+
+		raw, ok := secret.Data["release"]
+		if !ok {
+			continue
+		}
+
+		name, revision, err := decodeReleaseSecretData(string(raw))
+		if err != nil {
+			return 0, false, fmt.Errorf(
+				"unable to decode release storage Secret %s/%s: %w",
+				node.GetNamespace(),
+				node.GetName(),
+				err,
+			)
+		}
+		if name != releaseName {
+			continue
+		}
+		if !found || revision > latest {
+			found = true
+			latest = revision
+		}
+	}
+	return latest, found, nil
+}
+
+// magicGzip is the gzip header, used to detect whether a release storage
+// Secret predates Helm's switch to compressing the encoded release (see
+// helm.sh/helm/v3/pkg/storage/driver.decodeRelease).
+var magicGzip = []byte{0x1f, 0x8b, 0x08}
+
+// decodeReleaseSecretData decodes data (a release storage Secret's
+// already-unwrapped "release" data entry: base64-encoded, optionally
+// gzip-compressed JSON), the same way
+// helm.sh/helm/v3/pkg/storage/driver.decodeRelease does, and returns the
+// encoded release's name and revision number.
+func decodeReleaseSecretData(data string) (name string, revision int, err error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", 0, fmt.Errorf("unable to base64-decode release data: %w", err)
+	}
+
+	if len(raw) > 3 && bytes.Equal(raw[0:3], magicGzip) {
+		reader, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return "", 0, fmt.Errorf("unable to gunzip release data: %w", err)
+		}
+		defer reader.Close()
+
+		raw, err = io.ReadAll(reader)
+		if err != nil {
+			return "", 0, fmt.Errorf("unable to read release data: %w", err)
+		}
+	}
+
+	var release releasepkg.Release
+	if err := json.Unmarshal(raw, &release); err != nil {
+		return "", 0, fmt.Errorf("unable to unmarshal release data: %w", err)
+	}
+	return release.Name, release.Version, nil
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	releasepkg "helm.sh/helm/v3/pkg/release"
+)
+
+func mustEncodeReleaseSecretData(g gomega.Gomega, name string, revision int) string {
+	data, err := json.Marshal(releasepkg.Release{Name: name, Version: revision})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err = writer.Write(data)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(writer.Close()).To(gomega.Succeed())
+
+	return base64.StdEncoding.EncodeToString(compressed.Bytes())
+}
+
+func mustReleaseSecretNode(g gomega.Gomega, name, namespace, release string, revision int) string {
+	// A real Secret manifest's data values are themselves base64-encoded
+	// on top of whatever bytes they hold, so the Helm release data (already
+	// base64(gzip(json))) gets one more layer of base64 here, matching
+	// what decodeToObject into a corev1.Secret would unwrap back to the
+	// Helm-encoded string.
+	releaseData := base64.StdEncoding.EncodeToString(
+		[]byte(mustEncodeReleaseSecretData(g, release, revision)),
+	)
+	return strings.Join([]string{
+		"apiVersion: v1",
+		"kind: Secret",
+		"metadata:",
+		fmt.Sprintf("  name: %s", name),
+		fmt.Sprintf("  namespace: %s", namespace),
+		"type: helm.sh/release.v1",
+		"data:",
+		fmt.Sprintf("  release: %s", releaseData),
+	}, "\n")
+}
+
+var _ = ginkgo.Describe("findPreviousRevision", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("returns the highest revision stored for the release", func() {
+		nodes := mustParseValuesNodes(g, strings.Join([]string{
+			mustReleaseSecretNode(g, "sh.helm.release.v1.app.v1", "testns", "app", 1),
+			mustReleaseSecretNode(g, "sh.helm.release.v1.app.v2", "testns", "app", 2),
+			mustReleaseSecretNode(g, "sh.helm.release.v1.other.v5", "testns", "other", 5),
+		}, "\n---\n"))
+
+		revision, found, err := findPreviousRevision(nodes, "app", "testns")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(found).To(gomega.BeTrue())
+		g.Expect(revision).To(gomega.Equal(2))
+	})
+
+	ginkgo.It("ignores storage Secrets from other namespaces and non-Secret resources", func() {
+		nodes := mustParseValuesNodes(g, strings.Join([]string{
+			mustReleaseSecretNode(g, "sh.helm.release.v1.app.v3", "othernamespace", "app", 3),
+			strings.Join([]string{
+				"apiVersion: v1",
+				"kind: ConfigMap",
+				"metadata:",
+				"  name: plain",
+				"  namespace: testns",
+			}, "\n"),
+		}, "\n---\n"))
+
+		_, found, err := findPreviousRevision(nodes, "app", "testns")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(found).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("reports not found when no matching release is stored", func() {
+		_, found, err := findPreviousRevision(nil, "app", "testns")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(found).To(gomega.BeFalse())
+	})
+})
+
+var _ = ginkgo.Describe("releaseOptionsFor", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("always installs at revision 1 under RenderModeInstall", func() {
+		nodes := mustParseValuesNodes(g, mustReleaseSecretNode(g, "sh.helm.release.v1.app.v4", "testns", "app", 4))
+
+		revision, isInstall, isUpgrade, err := releaseOptionsFor(RenderModeInstall, "app", "testns", nodes)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(revision).To(gomega.Equal(1))
+		g.Expect(isInstall).To(gomega.BeTrue())
+		g.Expect(isUpgrade).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("installs at revision 1 under the zero value", func() {
+		revision, isInstall, isUpgrade, err := releaseOptionsFor("", "app", "testns", nil)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(revision).To(gomega.Equal(1))
+		g.Expect(isInstall).To(gomega.BeTrue())
+		g.Expect(isUpgrade).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("upgrades from the previous revision under RenderModeAuto when one is found", func() {
+		nodes := mustParseValuesNodes(g, mustReleaseSecretNode(g, "sh.helm.release.v1.app.v4", "testns", "app", 4))
+
+		revision, isInstall, isUpgrade, err := releaseOptionsFor(RenderModeAuto, "app", "testns", nodes)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(revision).To(gomega.Equal(5))
+		g.Expect(isInstall).To(gomega.BeFalse())
+		g.Expect(isUpgrade).To(gomega.BeTrue())
+	})
+
+	ginkgo.It("falls back to a first install under RenderModeAuto when no prior revision is found", func() {
+		revision, isInstall, isUpgrade, err := releaseOptionsFor(RenderModeAuto, "app", "testns", nil)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(revision).To(gomega.Equal(1))
+		g.Expect(isInstall).To(gomega.BeTrue())
+		g.Expect(isUpgrade).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("upgrades to revision 2 under RenderModeUpgrade when no prior revision is found", func() {
+		revision, isInstall, isUpgrade, err := releaseOptionsFor(RenderModeUpgrade, "app", "testns", nil)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(revision).To(gomega.Equal(2))
+		g.Expect(isInstall).To(gomega.BeFalse())
+		g.Expect(isUpgrade).To(gomega.BeTrue())
+	})
+})
@@ -14,11 +14,14 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	helmv2beta2 "github.com/fluxcd/helm-controller/api/v2beta2"
 	"github.com/fluxcd/pkg/git"
 	"github.com/fluxcd/pkg/git/gogit"
 	"github.com/fluxcd/pkg/git/repository"
+	"golang.org/x/time/rate"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/engine"
@@ -64,12 +67,48 @@ type gitClientFactoryFunc func(
 ) (GitClientInterface, error)
 
 type loaderConfig struct {
-	ctx              context.Context
-	logger           *slog.Logger
-	gitClientFactory gitClientFactoryFunc
-	cacheRoot        string
-	chartCache       map[string]*chart.Chart
-	credentials      Credentials
+	ctx                 context.Context
+	logger              *slog.Logger
+	gitClientFactory    gitClientFactoryFunc
+	ociPullerFactory    ociPullerFactoryFunc
+	bucketClientFactory bucketClientFactoryFunc
+	cacheRoot           string
+	chartCache          *chartCacheStore
+	diskChartCache      *diskChartCache
+	credentials         Credentials
+	repoLock            *repositoryLock
+	// verifyProvenance and keyringFile set the default chart provenance
+	// verification policy (see --verify/--keyring), overridable per
+	// repository via the "verify"/"keyring" Credentials entries.
+	verifyProvenance bool
+	keyringFile      string
+	// verification and signatureVerifier set the default OCI chart
+	// signature verification policy (see --verify-signatures and related
+	// flags), overridable per repository via RepositoryCreds's
+	// VerificationPublicKeys. signatureVerifier is shared across the whole
+	// run so verification results are cached by digest.
+	verification      ChartVerificationConfig
+	signatureVerifier *chartSignatureVerifier
+	// retry and rateLimiter govern how transient errors from Helm
+	// repository and OCI registry operations (logins, tag listing, index
+	// and chart downloads) are retried and throttled; see
+	// HelmReleaseExpander.SetRetryPolicy/SetRateLimit. A zero retry and a
+	// nil rateLimiter both fall back to sensible defaults (see
+	// RetryConfig.orDefault and hostRateLimiter.wait).
+	retry       RetryConfig
+	rateLimiter *hostRateLimiter
+	// repoNodes holds the repository objects (GitRepository,
+	// HelmRepository, OCIRepository, Bucket) declared in the input
+	// manifest, so that Chart.yaml dependencies written as alias:<name>
+	// can be resolved against them. It is nil when that context is
+	// unavailable.
+	repoNodes []*yaml.RNode
+	// seenCharts and buildReport together back DependencyReport
+	// collection for BuildReport; both are nil unless the current build
+	// is collecting one, in which case they are shared across the whole
+	// recursive dependency walk.
+	seenCharts  map[*chart.Chart]struct{}
+	buildReport *[]DependencyReport
 }
 
 type repositoryLoaderFactory func(config loaderConfig) repositoryLoader
@@ -110,6 +149,8 @@ func getRepoFactory(
 		return newGitRepositoryLoader, nil
 	case "OCIRepository":
 		return newOciRepositoryLoader, nil
+	case "Bucket":
+		return newBucketRepositoryLoader, nil
 	default:
 		return nil, fmt.Errorf(
 			"unknown kind %s for repository %s/%s",
@@ -120,29 +161,36 @@ func getRepoFactory(
 	}
 }
 
-func getRepoFactoryByURL(repoURL string) (repositoryLoaderFactory, error) {
+func getRepoFactoryByURL(
+	repoURL string,
+) (repositoryLoaderFactory, DependencySourceKind, error) {
 	var result repositoryLoaderFactory
+	var source DependencySourceKind
 
 	parsedURL, err := url.Parse(repoURL)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse chart repository URL %s", err)
+		return nil, "", fmt.Errorf("unable to parse chart repository URL %s", err)
 	}
 
 	switch parsedURL.Scheme {
 	case "https", "http":
 		if parsedURL.User.Username() == "git" {
 			result = newGitRepositoryLoader
+			source = DependencySourceGit
 		} else {
 			result = newHelmRepositoryLoader
+			source = DependencySourceHelm
 		}
 	case "ssh":
 		result = newGitRepositoryLoader
+		source = DependencySourceGit
 	case "oci":
 		result = newOciRepositoryLoader
+		source = DependencySourceOCI
 	default:
-		return nil, fmt.Errorf("unknown type for repository URL %s", repoURL)
+		return nil, "", fmt.Errorf("unknown type for repository URL %s", repoURL)
 	}
-	return result, nil
+	return result, source, nil
 }
 
 func getLoaderForRepo(
@@ -160,13 +208,13 @@ func getLoaderForRepo(
 func getLoaderForRepoURL(
 	repoURL string,
 	config loaderConfig,
-) (repositoryLoader, error) {
-	factory, err := getRepoFactoryByURL(repoURL)
+) (repositoryLoader, DependencySourceKind, error) {
+	factory, source, err := getRepoFactoryByURL(repoURL)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return factory(config), nil
+	return factory(config), source, nil
 }
 
 func joinPath(a string, b string) string {
@@ -203,50 +251,80 @@ func getCachePathForRepo(cacheRoot string, repoURL string) (string, error) {
 	return path.Join(cacheRoot, repoPath), nil
 }
 
-// loadRepositoryChart downloads the chart and returns it.
+// loadRepositoryChart downloads the chart and returns it along with a
+// BuildReport describing how its dependencies (including transitive
+// ones) were resolved. cacheRoot is shared across all HelmReleases
+// expanded in the same run so that repoLock can deduplicate concurrent
+// work (clones, index downloads) against the same repository.
+// manifestNodes supplies the other repository objects declared in the
+// input stream, for resolving Chart.yaml dependencies written as
+// alias:<name>.
 func loadRepositoryChart(
 	ctx context.Context,
 	logger *slog.Logger,
 	gitClientFactory gitClientFactoryFunc,
-	chartCache map[string]*chart.Chart,
+	ociPullerFactory ociPullerFactoryFunc,
+	bucketClientFactory bucketClientFactoryFunc,
+	cacheRoot string,
+	repoLock *repositoryLock,
+	chartCache *chartCacheStore,
+	diskChartCache *diskChartCache,
 	credentials Credentials,
+	verifyProvenance bool,
+	keyringFile string,
+	verification ChartVerificationConfig,
+	signatureVerifier *chartSignatureVerifier,
+	retry RetryConfig,
+	rateLimiter *hostRateLimiter,
 	release *helmv2beta2.HelmRelease,
 	repoNode *yaml.RNode,
-) (*chart.Chart, error) {
-	cacheRoot, err := os.MkdirTemp("", "chart-repo-cache-")
-	if err != nil {
-		return nil, fmt.Errorf(
-			"unable to create a cache dir for repo %s/%s/%s: %w",
-			repoNode.GetKind(),
-			repoNode.GetNamespace(),
-			repoNode.GetName(),
-			err,
-		)
-	}
-	defer os.RemoveAll(cacheRoot) // TODO(vlad): Find way to persist the cache.
-
+	manifestNodes []*yaml.RNode,
+) (*chart.Chart, BuildReport, error) {
+	report := &[]DependencyReport{}
 	loader, err := getLoaderForRepo(
 		repoNode,
 		loaderConfig{
 			ctx,
 			logger,
 			gitClientFactory,
+			ociPullerFactory,
+			bucketClientFactory,
 			cacheRoot,
 			chartCache,
+			diskChartCache,
 			credentials,
+			repoLock,
+			verifyProvenance,
+			keyringFile,
+			verification,
+			signatureVerifier,
+			retry,
+			rateLimiter,
+			manifestNodes,
+			map[*chart.Chart]struct{}{},
+			report,
 		},
 	)
 	if err != nil {
-		return nil, err
+		return nil, BuildReport{}, err
 	}
 
-	return loader.loadRepositoryChart(
+	resultChart, err := loader.loadRepositoryChart(
 		repoNode,
 		"",
 		nil,
 		release.Spec.Chart.Spec.Chart,
 		release.Spec.Chart.Spec.Version,
 	)
+	if err != nil {
+		return nil, BuildReport{}, err
+	}
+
+	return resultChart, BuildReport{
+		ChartName:    resultChart.Name(),
+		ChartVersion: resultChart.Metadata.Version,
+		Dependencies: *report,
+	}, nil
 }
 
 func loadChartDependencies(
@@ -254,12 +332,78 @@ func loadChartDependencies(
 	parentChart *chart.Chart,
 	parentContext *chartContext,
 ) error {
+	var chartPath string
+	if parentContext != nil {
+		chartPath = path.Join(parentContext.localRepoPath, parentContext.chartName)
+	}
+	resolver := NewResolver(chartPath, config.cacheRoot)
+	lock, err := resolver.Resolve(
+		parentChart.Metadata.Dependencies,
+		config.repoNodes,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"unable to resolve dependencies for chart %s: %w",
+			parentChart.Name(),
+			err,
+		)
+	}
+	pinnedVersions := map[string]string{}
+	for _, locked := range lock.Dependencies {
+		pinnedVersions[dependencyLockKey(locked)] = locked.Version
+	}
+
 	for _, dependency := range parentChart.Metadata.Dependencies {
+		versionSpec := dependency.Version
+		if pinned, ok := pinnedVersions[dependencyLockKey(dependency)]; ok {
+			versionSpec = pinned
+		}
+
 		if dependency.Repository == "" {
 			// This is a bundled chart, and those do not have repository
 			// information and are not addressable outside of the parent chart.
+			recordDependencyResolution(config, DependencyReport{
+				Name:            dependency.Name,
+				VersionSpec:     dependency.Version,
+				ResolvedVersion: dependency.Version,
+				Source:          DependencySourceBundled,
+			})
 			continue
 		}
+
+		if aliasName, ok := strings.CutPrefix(dependency.Repository, "alias:"); ok {
+			dependencyChart, err := loadAliasedDependencyChart(config, aliasName, dependency, versionSpec)
+			if err != nil {
+				return fmt.Errorf(
+					"unable to load chart %s/%s via %s (a dependency of %s): %w",
+					dependency.Name,
+					dependency.Version,
+					dependency.Repository,
+					parentChart.Name(),
+					err,
+				)
+			}
+			if err := resolver.VerifyDependencyArtifact(dependency, versionSpec, dependencyChart); err != nil {
+				return fmt.Errorf(
+					"chart %s/%s (a dependency of %s): %w",
+					dependency.Name,
+					dependency.Version,
+					parentChart.Name(),
+					err,
+				)
+			}
+			recordDependencyResolution(config, DependencyReport{
+				Name:            dependency.Name,
+				VersionSpec:     dependency.Version,
+				ResolvedVersion: dependencyChart.Metadata.Version,
+				Source:          DependencySourceAlias,
+				Repository:      dependency.Repository,
+				CacheHit:        config.markSeen(dependencyChart),
+			})
+			parentChart.AddDependency(dependencyChart)
+			continue
+		}
+
 		repoURL, err := normalizeURL(dependency.Repository)
 		if err != nil {
 			return fmt.Errorf(
@@ -276,18 +420,20 @@ func loadChartDependencies(
 			parsedURL.Path = path.Join("..", parsedURL.Path)
 		}
 		var dependencyChart *chart.Chart
+		var source DependencySourceKind
 		switch parsedURL.Scheme {
 		case "file", "":
+			source = DependencySourceLocal
 			dependencyChart, err = parentContext.loader.loadRepositoryChart(
 				parentContext.repoNode,
 				"",
 				parentContext,
 				joinPath(parentContext.chartName, parsedURL.Path),
-				dependency.Version,
+				versionSpec,
 			)
 		default:
 			var loader repositoryLoader
-			loader, err = getLoaderForRepoURL(repoURL, config)
+			loader, source, err = getLoaderForRepoURL(repoURL, config)
 			if err != nil {
 				return fmt.Errorf(
 					"unable to get loader for chart %s/%s in %s (a dependency of %s): %w",
@@ -304,7 +450,7 @@ func loadChartDependencies(
 				repoURL,
 				nil,
 				dependency.Name,
-				dependency.Version,
+				versionSpec,
 			)
 		}
 		if err != nil {
@@ -317,21 +463,85 @@ func loadChartDependencies(
 				err,
 			)
 		}
+		if err := resolver.VerifyDependencyArtifact(dependency, versionSpec, dependencyChart); err != nil {
+			return fmt.Errorf(
+				"chart %s/%s from %s (a dependency of %s): %w",
+				dependency.Name,
+				dependency.Version,
+				repoURL,
+				parentChart.Name(),
+				err,
+			)
+		}
+		recordDependencyResolution(config, DependencyReport{
+			Name:            dependency.Name,
+			VersionSpec:     dependency.Version,
+			ResolvedVersion: dependencyChart.Metadata.Version,
+			Source:          source,
+			Repository:      repoURL,
+			CacheHit:        config.markSeen(dependencyChart),
+		})
 		parentChart.AddDependency(dependencyChart)
 	}
 	return nil
 }
 
+// loadAliasedDependencyChart resolves a Chart.yaml dependency whose
+// repository field is alias:<aliasName> against the repository objects
+// declared in the input manifest (config.repoNodes).
+func loadAliasedDependencyChart(
+	config loaderConfig,
+	aliasName string,
+	dependency *chart.Dependency,
+	versionSpec string,
+) (*chart.Chart, error) {
+	repoNode, err := findAliasedRepoNode(config.repoNodes, aliasName)
+	if err != nil {
+		return nil, err
+	}
+
+	loader, err := getLoaderForRepo(repoNode, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return loader.loadRepositoryChart(
+		repoNode,
+		"",
+		nil,
+		dependency.Name,
+		versionSpec,
+	)
+}
+
 func expandHelmRelease(
 	ctx context.Context,
 	logger *slog.Logger,
 	gitClientFactory gitClientFactoryFunc,
+	ociPullerFactory ociPullerFactoryFunc,
+	bucketClientFactory bucketClientFactoryFunc,
+	cacheRoot string,
+	repoLock *repositoryLock,
 	kubeVersion *chartutil.KubeVersion,
 	apiVersions []string,
-	chartCache map[string]*chart.Chart,
+	chartCache *chartCacheStore,
+	diskChartCache *diskChartCache,
 	credentials Credentials,
+	verifyProvenance bool,
+	keyringFile string,
+	verification ChartVerificationConfig,
+	signatureVerifier *chartSignatureVerifier,
+	retry RetryConfig,
+	rateLimiter *hostRateLimiter,
+	valuesSource ValuesSource,
+	valuesIdx *valuesIndex,
+	hookPolicy HookPolicy,
+	crdPolicy CRDPolicy,
+	postRenderer PostRenderer,
+	renderMode RenderMode,
 	releaseNode *yaml.RNode,
 	repoNode *yaml.RNode,
+	manifestNodes []*yaml.RNode,
 ) ([]*yaml.RNode, error) {
 	var release helmv2beta2.HelmRelease
 	err := decodeToObject(releaseNode, &release)
@@ -350,14 +560,26 @@ func expandHelmRelease(
 		)
 	}
 
-	chart, err := loadRepositoryChart(
+	chart, buildReport, err := loadRepositoryChart(
 		ctx,
 		logger,
 		gitClientFactory,
+		ociPullerFactory,
+		bucketClientFactory,
+		cacheRoot,
+		repoLock,
 		chartCache,
+		diskChartCache,
 		credentials,
+		verifyProvenance,
+		keyringFile,
+		verification,
+		signatureVerifier,
+		retry,
+		rateLimiter,
 		&release,
 		repoNode,
+		manifestNodes,
 	)
 	if err != nil {
 		return nil, fmt.Errorf(
@@ -368,9 +590,27 @@ func expandHelmRelease(
 			err,
 		)
 	}
+	logBuildReport(logger, buildReport)
+
+	releaseValues, err := resolveValuesFrom(ctx, &release, valuesIdx, valuesSource)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to resolve values for Helm release %s/%s: %w",
+			release.Namespace,
+			release.Name,
+			err,
+		)
+	}
+
+	// loadRepositoryChart may return a chart shared (via chartCache) with
+	// other HelmReleases being expanded concurrently. Deep-copy it before
+	// ProcessDependenciesWithMerge mutates its dependency tree in place, so
+	// that one release's condition/tags decisions can never race with or
+	// leak into another's.
+	chart = deepCopyChart(chart)
 
 	// Remove charts disabled by conditions.
-	err = chartutil.ProcessDependenciesWithMerge(chart, release.GetValues())
+	err = chartutil.ProcessDependenciesWithMerge(chart, releaseValues)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"unable to process dependencies for chart %s: %w",
@@ -379,7 +619,7 @@ func expandHelmRelease(
 		)
 	}
 
-	values, err := chartutil.CoalesceValues(chart, release.GetValues())
+	values, err := chartutil.CoalesceValues(chart, releaseValues)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"unable to coalesce values from the chart for release %s/%s: %w",
@@ -406,12 +646,27 @@ func expandHelmRelease(
 		releaseName = fmt.Sprintf("%s-%s", targetNamespace, release.Name)
 	}
 
+	revision, isInstall, isUpgrade, err := releaseOptionsFor(
+		renderMode,
+		releaseName,
+		release.GetStorageNamespace(),
+		manifestNodes,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to determine release state for Helm release %s/%s: %w",
+			release.Namespace,
+			release.Name,
+			err,
+		)
+	}
+
 	options := chartutil.ReleaseOptions{
 		Name:      releaseName,
 		Namespace: targetNamespace,
-		Revision:  1,
-		IsInstall: true,
-		IsUpgrade: false,
+		Revision:  revision,
+		IsInstall: isInstall,
+		IsUpgrade: isUpgrade,
 	}
 	valuesToRender, err := chartutil.ToRenderValues(chart, values, options, capabilities)
 	if err != nil {
@@ -459,6 +714,8 @@ func expandHelmRelease(
 		}
 	}
 
+	results = filterHooks(results, hookPolicy)
+
 	filter := &namespace.Filter{
 		Namespace:              release.Namespace,
 		UnsetOnly:              true,
@@ -474,6 +731,48 @@ func expandHelmRelease(
 			err,
 		)
 	}
+
+	for _, postRendererSpec := range release.Spec.PostRenderers {
+		if postRendererSpec.Kustomize == nil {
+			continue
+		}
+		results, err = NewKustomizePostRenderer(postRendererSpec.Kustomize).Run(results)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"unable to apply Kustomize post-renderer for Helm release %s/%s: %w",
+				release.Namespace,
+				release.Name,
+				err,
+			)
+		}
+	}
+
+	if postRenderer != nil {
+		results, err = postRenderer.Run(results)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"unable to apply post-renderer for Helm release %s/%s: %w",
+				release.Namespace,
+				release.Name,
+				err,
+			)
+		}
+	}
+
+	if crdPolicy != CRDPolicySkip && crdPolicy != "" {
+		crdNodes, err := crdNodesForChart(chart)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"unable to parse CRDs for chart %s: %w",
+				chart.Name(),
+				err,
+			)
+		}
+		// CRDs are cluster-scoped, so they deliberately bypass the
+		// namespace filter above.
+		results = append(results, crdNodes...)
+	}
+
 	return results, nil
 }
 
@@ -563,66 +862,182 @@ func (filter *releaseRepoFilter) Filter(
 }
 
 type releaseRepoRenderer struct {
-	ctx              context.Context
-	logger           *slog.Logger
-	gitClientFactory gitClientFactoryFunc
-	kubeVersion      *chartutil.KubeVersion
-	apiVersions      []string
-	chartCache       map[string]*chart.Chart
-	credentials      Credentials
-	pairs            *[]releaseRepo
+	ctx                 context.Context
+	logger              *slog.Logger
+	gitClientFactory    gitClientFactoryFunc
+	ociPullerFactory    ociPullerFactoryFunc
+	bucketClientFactory bucketClientFactoryFunc
+	cacheRoot           string
+	repoLock            *repositoryLock
+	parallelism         int
+	kubeVersion         *chartutil.KubeVersion
+	apiVersions         []string
+	chartCache          *chartCacheStore
+	diskChartCache      *diskChartCache
+	credentials         Credentials
+	verifyProvenance    bool
+	keyringFile         string
+	verification        ChartVerificationConfig
+	signatureVerifier   *chartSignatureVerifier
+	retry               RetryConfig
+	rateLimiter         *hostRateLimiter
+	valuesSource        ValuesSource
+	hookPolicy          HookPolicy
+	crdPolicy           CRDPolicy
+	postRenderer        PostRenderer
+	renderMode          RenderMode
+	pairs               *[]releaseRepo
 }
 
 func newReleaseRepoRenderer(
 	ctx context.Context,
 	logger *slog.Logger,
 	gitClientFactory gitClientFactoryFunc,
+	ociPullerFactory ociPullerFactoryFunc,
+	bucketClientFactory bucketClientFactoryFunc,
+	cacheRoot string,
+	repoLock *repositoryLock,
+	parallelism int,
 	kubeVersion *chartutil.KubeVersion,
 	apiVersions []string,
-	chartCache map[string]*chart.Chart,
+	chartCache *chartCacheStore,
+	diskChartCache *diskChartCache,
 	credentials Credentials,
+	verifyProvenance bool,
+	keyringFile string,
+	verification ChartVerificationConfig,
+	signatureVerifier *chartSignatureVerifier,
+	retry RetryConfig,
+	rateLimiter *hostRateLimiter,
+	valuesSource ValuesSource,
+	hookPolicy HookPolicy,
+	crdPolicy CRDPolicy,
+	postRenderer PostRenderer,
+	renderMode RenderMode,
 	pairs *[]releaseRepo,
 ) *releaseRepoRenderer {
 	return &releaseRepoRenderer{
-		ctx:              ctx,
-		logger:           logger,
-		gitClientFactory: gitClientFactory,
-		kubeVersion:      kubeVersion,
-		apiVersions:      apiVersions,
-		chartCache:       chartCache,
-		credentials:      credentials,
-		pairs:            pairs,
+		ctx:                 ctx,
+		logger:              logger,
+		gitClientFactory:    gitClientFactory,
+		ociPullerFactory:    ociPullerFactory,
+		bucketClientFactory: bucketClientFactory,
+		cacheRoot:           cacheRoot,
+		repoLock:            repoLock,
+		parallelism:         parallelism,
+		kubeVersion:         kubeVersion,
+		apiVersions:         apiVersions,
+		chartCache:          chartCache,
+		diskChartCache:      diskChartCache,
+		credentials:         credentials,
+		verifyProvenance:    verifyProvenance,
+		keyringFile:         keyringFile,
+		verification:        verification,
+		signatureVerifier:   signatureVerifier,
+		retry:               retry,
+		rateLimiter:         rateLimiter,
+		valuesSource:        valuesSource,
+		hookPolicy:          hookPolicy,
+		crdPolicy:           crdPolicy,
+		postRenderer:        postRenderer,
+		renderMode:          renderMode,
+		pairs:               pairs,
 	}
 }
 
+// Filter expands every HelmRelease/repository pair, running up to
+// renderer.parallelism expansions concurrently. Results are collected into
+// a slice indexed by the pair's position so that the expansion order
+// doesn't depend on which goroutine finishes first; the subsequent sort
+// below then makes the final document order fully deterministic. nodes is
+// also indexed once up front by newValuesIndex so that every expansion can
+// resolve its HelmRelease's valuesFrom references against the same
+// ConfigMaps/Secrets without rescanning nodes itself. When renderer.crdPolicy
+// is CRDPolicySeparate, the sort additionally places every
+// CustomResourceDefinition ahead of all other resources.
 func (renderer *releaseRepoRenderer) Filter(
 	nodes []*yaml.RNode,
 ) ([]*yaml.RNode, error) {
-	result := []*yaml.RNode{}
+	pairs := *renderer.pairs
+	expanded := make([][]*yaml.RNode, len(pairs))
+	errs := make([]error, len(pairs))
+	valuesIdx := newValuesIndex(nodes)
 
-	for _, pair := range *renderer.pairs {
-		expanded, err := expandHelmRelease(
-			renderer.ctx,
-			renderer.logger,
-			renderer.gitClientFactory,
-			renderer.kubeVersion,
-			renderer.apiVersions,
-			renderer.chartCache,
-			renderer.credentials,
-			pair.release,
-			pair.repo,
-		)
+	parallelism := renderer.parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	var wg sync.WaitGroup
+	tokens := make(chan struct{}, parallelism)
+	for i, pair := range pairs {
+		wg.Add(1)
+		tokens <- struct{}{}
+		go func(i int, pair releaseRepo) {
+			defer wg.Done()
+			defer func() { <-tokens }()
+			expanded[i], errs[i] = expandHelmRelease(
+				renderer.ctx,
+				renderer.logger,
+				renderer.gitClientFactory,
+				renderer.ociPullerFactory,
+				renderer.bucketClientFactory,
+				renderer.cacheRoot,
+				renderer.repoLock,
+				renderer.kubeVersion,
+				renderer.apiVersions,
+				renderer.chartCache,
+				renderer.diskChartCache,
+				renderer.credentials,
+				renderer.verifyProvenance,
+				renderer.keyringFile,
+				renderer.verification,
+				renderer.signatureVerifier,
+				renderer.retry,
+				renderer.rateLimiter,
+				renderer.valuesSource,
+				valuesIdx,
+				renderer.hookPolicy,
+				renderer.crdPolicy,
+				renderer.postRenderer,
+				renderer.renderMode,
+				pair.release,
+				pair.repo,
+				nodes,
+			)
+		}(i, pair)
+	}
+	wg.Wait()
+
+	result := []*yaml.RNode{}
+	var wrapped []error
+	for i, err := range errs {
 		if err != nil {
-			return nil, fmt.Errorf(
+			wrapped = append(wrapped, fmt.Errorf(
 				"unable to expand Helm release %s/%s: %w",
-				pair.release.GetNamespace(),
-				pair.release.GetName(),
+				pairs[i].release.GetNamespace(),
+				pairs[i].release.GetName(),
 				err,
-			)
+			))
+			continue
 		}
-		result = append(result, expanded...)
+		result = append(result, expanded[i]...)
+	}
+	if len(wrapped) > 0 {
+		return nil, errors.Join(wrapped...)
 	}
 	slices.SortStableFunc(result, func(a, b *yaml.RNode) int {
+		if renderer.crdPolicy == CRDPolicySeparate {
+			aIsCRD := a.GetKind() == "CustomResourceDefinition"
+			bIsCRD := b.GetKind() == "CustomResourceDefinition"
+			if aIsCRD != bIsCRD {
+				if aIsCRD {
+					return -1
+				}
+				return 1
+			}
+		}
+
 		aKind := a.GetKind()
 		bKind := b.GetKind()
 		if aKind < bKind {
@@ -660,23 +1075,150 @@ func (renderer *releaseRepoRenderer) Filter(
 }
 
 type HelmReleaseExpander struct {
-	ctx              context.Context
-	logger           *slog.Logger
-	gitClientFactory gitClientFactoryFunc
+	ctx                 context.Context
+	logger              *slog.Logger
+	gitClientFactory    gitClientFactoryFunc
+	ociPullerFactory    ociPullerFactoryFunc
+	bucketClientFactory bucketClientFactoryFunc
+	parallelism         int
+	diskChartCache      *diskChartCache
+	verifyProvenance    bool
+	keyringFile         string
+	verification        ChartVerificationConfig
+	signatureVerifier   *chartSignatureVerifier
+	retry               RetryConfig
+	rateLimiter         *hostRateLimiter
+	valuesSource        ValuesSource
+	hookPolicy          HookPolicy
+	crdPolicy           CRDPolicy
+	postRenderer        PostRenderer
+	renderMode          RenderMode
 }
 
+// NewHelmReleaseExpander creates a HelmReleaseExpander. parallelism bounds
+// how many HelmReleases are expanded concurrently by ExpandHelmReleases;
+// values less than 1 are treated as 1 (sequential expansion).
 func NewHelmReleaseExpander(
 	ctx context.Context,
 	logger *slog.Logger,
 	gitClientFactory gitClientFactoryFunc,
+	ociPullerFactory ociPullerFactoryFunc,
+	bucketClientFactory bucketClientFactoryFunc,
+	parallelism int,
 ) *HelmReleaseExpander {
 	return &HelmReleaseExpander{
-		ctx:              ctx,
-		logger:           logger,
-		gitClientFactory: gitClientFactory,
+		ctx:                 ctx,
+		logger:              logger,
+		gitClientFactory:    gitClientFactory,
+		ociPullerFactory:    ociPullerFactory,
+		bucketClientFactory: bucketClientFactory,
+		parallelism:         parallelism,
+		signatureVerifier:   newChartSignatureVerifier(),
+		rateLimiter:         newHostRateLimiter(rate.Inf, 0),
 	}
 }
 
+// SetDiskCache enables a persistent, content-addressable on-disk chart
+// cache rooted at dir, consulted by ExpandHelmReleases and BuildChart in
+// addition to the process-local in-memory cache, so that repeated
+// invocations of the command do not re-download unchanged charts. A zero
+// ttl means cached entries never expire on their own; see PruneChartCache
+// for removing expired ones. A zero or negative maxBytes disables
+// size-based eviction, leaving ttl (if set) as the only way entries are
+// reclaimed.
+func (expander *HelmReleaseExpander) SetDiskCache(dir string, ttl time.Duration, maxBytes int64) {
+	expander.diskChartCache = newDiskChartCache(dir, ttl, maxBytes)
+}
+
+// PruneChartCache removes on-disk chart cache entries (and any blobs left
+// unreferenced as a result) older than the TTL passed to SetDiskCache, as
+// of now. It is a no-op unless SetDiskCache has been called with a
+// nonzero ttl, and is meant to be run once before the cache is consulted,
+// e.g. at the start of an expand invocation.
+func (expander *HelmReleaseExpander) PruneChartCache(now time.Time) error {
+	if expander.diskChartCache == nil {
+		return nil
+	}
+	return expander.diskChartCache.gc(now)
+}
+
+// SetProvenanceVerification enables chart provenance (.prov) verification
+// against keyringFile for HTTP(S) Helm repositories, the way `helm pull
+// --verify` does. When required is true, every repository lacking a more
+// specific "verify"/"keyring" Credentials override must present a valid
+// signature; when false, verification is opportunistic and only attempted
+// for repositories whose credentials explicitly set verify: true.
+func (expander *HelmReleaseExpander) SetProvenanceVerification(keyringFile string, required bool) {
+	expander.keyringFile = keyringFile
+	expander.verifyProvenance = required
+}
+
+// SetChartVerification enables cosign signature verification for OCI chart
+// artifacts, using config as the default policy for every OCI repository
+// that doesn't supply a more specific RepositoryCreds.VerificationPublicKeys
+// override.
+func (expander *HelmReleaseExpander) SetChartVerification(config ChartVerificationConfig) {
+	expander.verification = config
+}
+
+// SetRetryPolicy configures how transient errors from Helm repository and
+// OCI registry operations are retried; see RetryConfig. The zero value
+// (the default before this is called) falls back to defaultRetryConfig.
+func (expander *HelmReleaseExpander) SetRetryPolicy(config RetryConfig) {
+	expander.retry = config
+}
+
+// SetRateLimit throttles Helm repository and OCI registry operations to at
+// most limit requests per second per host, with bursts of up to burst
+// requests. Before this is called, operations are unthrottled.
+func (expander *HelmReleaseExpander) SetRateLimit(limit rate.Limit, burst int) {
+	expander.rateLimiter = newHostRateLimiter(limit, burst)
+}
+
+// SetValuesSource configures where HelmRelease spec.valuesFrom entries are
+// resolved from when the referenced ConfigMap/Secret isn't present in the
+// manifest stream being expanded, e.g. a live cluster or a directory of
+// manifest files. Before this is called, such references only resolve
+// against the input stream.
+func (expander *HelmReleaseExpander) SetValuesSource(source ValuesSource) {
+	expander.valuesSource = source
+}
+
+// SetHookPolicy controls which Helm hook resources ExpandHelmReleases
+// includes in its output; see HookPolicy. The zero value (the empty string,
+// the default before this is called) behaves like HookPolicyIncludeAll and
+// renders every hook.
+func (expander *HelmReleaseExpander) SetHookPolicy(policy HookPolicy) {
+	expander.hookPolicy = policy
+}
+
+// SetCRDPolicy controls whether and where ExpandHelmReleases emits the
+// CustomResourceDefinitions bundled in a chart's crds/ directory; see
+// CRDPolicy. The zero value (the empty string, the default before this is
+// called) behaves like CRDPolicySkip and omits them, matching prior
+// behavior.
+func (expander *HelmReleaseExpander) SetCRDPolicy(policy CRDPolicy) {
+	expander.crdPolicy = policy
+}
+
+// SetPostRenderer configures an additional PostRenderer applied to every
+// HelmRelease's rendered manifests, after any Kustomize overlay declared
+// in the release's own spec.postRenderers[], the way "helm template
+// --post-renderer" applies a single external plugin on top of whatever
+// the chart itself renders. Before this is called, only the release's own
+// spec.postRenderers[] entries (if any) are applied.
+func (expander *HelmReleaseExpander) SetPostRenderer(postRenderer PostRenderer) {
+	expander.postRenderer = postRenderer
+}
+
+// SetRenderMode controls whether ExpandHelmReleases renders each
+// HelmRelease as a first install or as an upgrade from a prior revision;
+// see RenderMode. The zero value (the empty string, the default before
+// this is called) behaves like RenderModeInstall, matching prior behavior.
+func (expander *HelmReleaseExpander) SetRenderMode(mode RenderMode) {
+	expander.renderMode = mode
+}
+
 func (expander *HelmReleaseExpander) ExpandHelmReleases(
 	credentials Credentials,
 	input io.Reader,
@@ -685,10 +1227,17 @@ func (expander *HelmReleaseExpander) ExpandHelmReleases(
 	apiVersions []string,
 	enableChartInMemoryCache bool,
 ) error {
-	var chartCache map[string]*chart.Chart
+	cacheRoot, err := os.MkdirTemp("", "fouskoti-repo-cache-")
+	if err != nil {
+		return fmt.Errorf("unable to create a repository cache directory: %w", err)
+	}
+	defer os.RemoveAll(cacheRoot)
+
+	var chartCache *chartCacheStore
 	if enableChartInMemoryCache {
-		chartCache = make(map[string]*chart.Chart)
+		chartCache = newChartCacheStore()
 	}
+	repoLock := newRepositoryLock()
 
 	var pairs []releaseRepo
 	filter1 := newReleaseRepoFilter(&pairs)
@@ -696,10 +1245,27 @@ func (expander *HelmReleaseExpander) ExpandHelmReleases(
 		expander.ctx,
 		expander.logger,
 		expander.gitClientFactory,
+		expander.ociPullerFactory,
+		expander.bucketClientFactory,
+		cacheRoot,
+		repoLock,
+		expander.parallelism,
 		kubeVersion,
 		apiVersions,
 		chartCache,
+		expander.diskChartCache,
 		credentials,
+		expander.verifyProvenance,
+		expander.keyringFile,
+		expander.verification,
+		expander.signatureVerifier,
+		expander.retry,
+		expander.rateLimiter,
+		expander.valuesSource,
+		expander.hookPolicy,
+		expander.crdPolicy,
+		expander.postRenderer,
+		expander.renderMode,
 		&pairs,
 	)
 
@@ -709,3 +1275,83 @@ func (expander *HelmReleaseExpander) ExpandHelmReleases(
 		Outputs: []kio.Writer{kio.ByteWriter{Writer: output}},
 	}.Execute()
 }
+
+// BuildChart resolves the chart for a single HelmRelease, including its
+// transitive dependencies, the same way ExpandHelmReleases does
+// internally, and returns it along with a BuildReport describing how
+// each dependency was resolved. It is meant for programmatic callers
+// that already have a parsed HelmRelease and the rest of the manifest,
+// rather than a full Reader/Writer stream to expand.
+//
+// manifestNodes supplies the other objects parsed from the input
+// stream (the GitRepository/HelmRepository/OCIRepository/Bucket
+// sources, and any alias:<name>-referenced ones) so that releaseNode's
+// sourceRef and Chart.yaml dependency aliases can be resolved against
+// them.
+func (expander *HelmReleaseExpander) BuildChart(
+	credentials Credentials,
+	releaseNode *yaml.RNode,
+	manifestNodes []*yaml.RNode,
+) (*chart.Chart, BuildReport, error) {
+	var release helmv2beta2.HelmRelease
+	if err := decodeToObject(releaseNode, &release); err != nil {
+		return nil, BuildReport{}, fmt.Errorf("unable to decode HelmRelease: %w", err)
+	}
+
+	repoNode, err := getRepositoryForHelmRelease(manifestNodes, releaseNode)
+	if err != nil {
+		return nil, BuildReport{}, fmt.Errorf(
+			"unable to find repository for HelmRelease %s/%s: %w",
+			release.Namespace,
+			release.Name,
+			err,
+		)
+	}
+	if repoNode == nil {
+		return nil, BuildReport{}, fmt.Errorf(
+			"missing chart repository for Helm release %s/%s",
+			release.Namespace,
+			release.Name,
+		)
+	}
+
+	cacheRoot, err := os.MkdirTemp("", "fouskoti-repo-cache-")
+	if err != nil {
+		return nil, BuildReport{}, fmt.Errorf("unable to create a repository cache directory: %w", err)
+	}
+	defer os.RemoveAll(cacheRoot)
+
+	resultChart, buildReport, err := loadRepositoryChart(
+		expander.ctx,
+		expander.logger,
+		expander.gitClientFactory,
+		expander.ociPullerFactory,
+		expander.bucketClientFactory,
+		cacheRoot,
+		newRepositoryLock(),
+		nil,
+		expander.diskChartCache,
+		credentials,
+		expander.verifyProvenance,
+		expander.keyringFile,
+		expander.verification,
+		expander.signatureVerifier,
+		expander.retry,
+		expander.rateLimiter,
+		&release,
+		repoNode,
+		manifestNodes,
+	)
+	if err != nil {
+		return nil, BuildReport{}, fmt.Errorf(
+			"unable to load chart for %s %s/%s: %w",
+			repoNode.GetKind(),
+			repoNode.GetNamespace(),
+			repoNode.GetName(),
+			err,
+		)
+	}
+	logBuildReport(expander.logger, buildReport)
+
+	return resultChart, buildReport, nil
+}
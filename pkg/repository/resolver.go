@@ -0,0 +1,320 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+// dependencyLockKey identifies a Chart.yaml dependency within a Chart.lock,
+// the way Helm itself keys them: by name and repository URL (a chart may
+// depend on two same-named subcharts from different repositories).
+func dependencyLockKey(dependency *chart.Dependency) string {
+	return dependency.Name + "|" + dependency.Repository
+}
+
+// hashDependencies computes the digest Helm itself stores in Chart.lock
+// (see the unexported helm.sh/helm/v3/internal/resolver.HashReq, which
+// cannot be imported outside that module): a SHA-256 over the JSON
+// encoding of [req, lock], the Chart.yaml dependencies as requested and
+// as resolved into Chart.lock, in their original order. Matching this
+// formula exactly (rather than an independently-invented one) is what
+// lets a Chart.lock generated by a real "helm dependency update" be
+// consumed here without looking stale.
+func hashDependencies(req, lock []*chart.Dependency) (string, error) {
+	data, err := json.Marshal([2][]*chart.Dependency{req, lock})
+	if err != nil {
+		return "", fmt.Errorf("unable to encode dependencies for hashing: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// dependencyCacheKey returns a stable, order-independent key identifying
+// deps, used only to name a resolution cached under Resolver.cachepath
+// (see cacheLockFilePath); it has no relation to Helm's own Chart.lock
+// digest (see hashDependencies).
+func dependencyCacheKey(deps []*chart.Dependency) string {
+	keys := make([]string, 0, len(deps))
+	for _, dependency := range deps {
+		keys = append(keys, fmt.Sprintf(
+			"%s|%s|%s",
+			dependency.Name,
+			dependency.Repository,
+			dependency.Version,
+		))
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintln(hash, key)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Resolver resolves a chart's Chart.yaml dependencies the way Helm's own
+// dependency manager does (see the unexported helm.sh/helm/v3/internal/resolver,
+// which cannot be imported outside that module): when the chart carries a
+// Chart.lock whose digest still matches its Chart.yaml dependencies, the
+// versions it pinned are used as-is. Otherwise each dependency's version is
+// left as the semver constraint written in Chart.yaml (e.g. "^1.2",
+// ">=1.0 <2.0", "*"), to be resolved against that dependency's repository
+// by the chart's loader, which already picks the highest matching version
+// (see getLatestMatchingVersion for OCI tags and repo.IndexFile.Get for
+// Helm repository indexes).
+type Resolver struct {
+	// chartpath is the on-disk directory of the chart being resolved, if
+	// one exists; Chart.lock is read from and written to
+	// chartpath/Chart.lock. It is empty for charts loaded from an archive
+	// rather than a directory (OCI and Helm repository charts), which have
+	// no standalone Chart.lock file for the resolver to consult.
+	chartpath string
+	// cachepath is a directory used to persist resolution results when
+	// chartpath is empty, keyed by a digest of the dependency list, so
+	// that repeated resolutions of the same Chart.yaml dependencies (for
+	// example, a subchart referenced by more than one parent chart in the
+	// same build) are not recomputed. It also holds the artifact digest
+	// store (see VerifyDependencyArtifact), since neither chart.Dependency
+	// nor chart.Lock have a field to carry a per-dependency content digest.
+	cachepath string
+}
+
+// NewResolver returns a Resolver for a chart rooted at chartpath (directory
+// charts only; pass "" for archive-loaded charts) that persists resolution
+// results under cachepath when chartpath is unavailable.
+func NewResolver(chartpath, cachepath string) *Resolver {
+	return &Resolver{chartpath: chartpath, cachepath: cachepath}
+}
+
+func (resolver *Resolver) lockFilePath() string {
+	if resolver.chartpath != "" {
+		return path.Join(resolver.chartpath, "Chart.lock")
+	}
+	return ""
+}
+
+func (resolver *Resolver) cacheLockFilePath(cacheKey string) string {
+	if resolver.cachepath == "" {
+		return ""
+	}
+	return path.Join(resolver.cachepath, "resolved-"+cacheKey+".lock")
+}
+
+// artifactDigestStorePath returns the path used to persist dependency
+// artifact digests across invocations, or "" when resolver.cachepath is
+// unset, which disables artifact digest pinning the same way an unset
+// --cache-dir disables the on-disk chart cache.
+func (resolver *Resolver) artifactDigestStorePath() string {
+	if resolver.cachepath == "" {
+		return ""
+	}
+	return path.Join(resolver.cachepath, "dependency-digests.json")
+}
+
+func readLockFile(lockPath string) (*chart.Lock, error) {
+	if lockPath == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(lockPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", lockPath, err)
+	}
+	var lock chart.Lock
+	if err := k8syaml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", lockPath, err)
+	}
+	return &lock, nil
+}
+
+func writeLockFile(lockPath string, lock *chart.Lock) error {
+	if lockPath == "" {
+		return nil
+	}
+	data, err := k8syaml.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("unable to encode %s: %w", lockPath, err)
+	}
+	if err := os.MkdirAll(path.Dir(lockPath), 0755); err != nil {
+		return fmt.Errorf("unable to create directory for %s: %w", lockPath, err)
+	}
+	if err := os.WriteFile(lockPath, data, 0600); err != nil {
+		return fmt.Errorf("unable to write %s: %w", lockPath, err)
+	}
+	return nil
+}
+
+func readArtifactDigests(storePath string) (map[string]string, error) {
+	digests := map[string]string{}
+	if storePath == "" {
+		return digests, nil
+	}
+	data, err := os.ReadFile(storePath)
+	if os.IsNotExist(err) {
+		return digests, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", storePath, err)
+	}
+	if err := json.Unmarshal(data, &digests); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", storePath, err)
+	}
+	return digests, nil
+}
+
+func writeArtifactDigests(storePath string, digests map[string]string) error {
+	if storePath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(digests, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode %s: %w", storePath, err)
+	}
+	if err := os.MkdirAll(path.Dir(storePath), 0755); err != nil {
+		return fmt.Errorf("unable to create directory for %s: %w", storePath, err)
+	}
+	if err := os.WriteFile(storePath, data, 0600); err != nil {
+		return fmt.Errorf("unable to write %s: %w", storePath, err)
+	}
+	return nil
+}
+
+// chartArtifactDigest returns the SHA-256 digest of c's packaged archive,
+// computed the same way cmd/mirror does to report a mirrored chart's
+// digest: by repackaging it with chartutil.Save and hashing the result.
+func chartArtifactDigest(c *chart.Chart) (string, error) {
+	tmpDir, err := os.MkdirTemp("", "fouskoti-resolver-")
+	if err != nil {
+		return "", fmt.Errorf("unable to create a temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tgzPath, err := chartutil.Save(c, tmpDir)
+	if err != nil {
+		return "", fmt.Errorf("unable to package chart %s: %w", c.Name(), err)
+	}
+	data, err := os.ReadFile(tgzPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to read packaged chart %s: %w", tgzPath, err)
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// Resolve determines the version each of deps should be loaded at,
+// returning the result as a *chart.Lock the way "helm dependency build"
+// would produce or consume one. repos is the set of repository objects
+// declared in the input manifest, reserved for resolving alias:<name>
+// dependencies in a future revision of this resolver; it is currently
+// unused since alias dependencies are not pinned by Chart.lock.
+//
+// If chartpath/Chart.lock exists, its digest must match the current
+// dependencies (computed the same way Helm's own resolver does, see
+// hashDependencies) or Resolve fails, the same as Helm refusing to build
+// dependencies against a lock file that is out of date with Chart.yaml.
+func (resolver *Resolver) Resolve(
+	deps []*chart.Dependency,
+	repos []*yaml.RNode,
+) (*chart.Lock, error) {
+	_ = repos
+
+	lockPath := resolver.lockFilePath()
+	if lockPath == "" {
+		lockPath = resolver.cacheLockFilePath(dependencyCacheKey(deps))
+	}
+	lock, err := readLockFile(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	if lock != nil {
+		digest, err := hashDependencies(deps, lock.Dependencies)
+		if err != nil {
+			return nil, err
+		}
+		if lock.Digest != digest {
+			return nil, fmt.Errorf(
+				"%s digest %s does not match its Chart.yaml dependencies (want %s); "+
+					"regenerate it (e.g. via helm dependency update) before resolving",
+				lockPath,
+				lock.Digest,
+				digest,
+			)
+		}
+		return lock, nil
+	}
+
+	digest, err := hashDependencies(deps, deps)
+	if err != nil {
+		return nil, err
+	}
+	lock = &chart.Lock{
+		Generated:    time.Now(),
+		Digest:       digest,
+		Dependencies: deps,
+	}
+	if err := writeLockFile(lockPath, lock); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// VerifyDependencyArtifact checks resolvedChart, the chart loaded for
+// dependency at the pinned version, against the SHA-256 digest recorded
+// the last time this name/repository/version triple was resolved, and
+// fails if they differ, the same way a locked package repository refuses
+// content that no longer matches its pinned hash. Neither chart.Dependency
+// nor chart.Lock (Helm's own Chart.lock format) carry a content digest
+// field, so this digest is tracked in a sidecar file under
+// resolver.cachepath instead of inside Chart.lock; when cachepath is
+// unset, verification is a no-op, the same as the on-disk chart cache
+// being disabled when no cache directory is configured.
+func (resolver *Resolver) VerifyDependencyArtifact(
+	dependency *chart.Dependency,
+	version string,
+	resolvedChart *chart.Chart,
+) error {
+	storePath := resolver.artifactDigestStorePath()
+	if storePath == "" {
+		return nil
+	}
+
+	digest, err := chartArtifactDigest(resolvedChart)
+	if err != nil {
+		return fmt.Errorf("unable to digest chart %s: %w", resolvedChart.Name(), err)
+	}
+
+	digests, err := readArtifactDigests(storePath)
+	if err != nil {
+		return err
+	}
+
+	key := dependencyLockKey(dependency) + "|" + version
+	if previous, ok := digests[key]; ok && previous != digest {
+		return fmt.Errorf(
+			"chart %s version %s from %s has digest %s, which does not match "+
+				"the previously resolved digest %s; the chart may have changed "+
+				"without its version changing",
+			dependency.Name,
+			version,
+			dependency.Repository,
+			digest,
+			previous,
+		)
+	}
+
+	digests[key] = digest
+	return writeArtifactDigests(storePath, digests)
+}
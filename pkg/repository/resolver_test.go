@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"os"
+	"path"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+var _ = ginkgo.Describe("Resolver", func() {
+	var g gomega.Gomega
+	var dir string
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+
+		var err error
+		dir, err = os.MkdirTemp("", "")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+	})
+
+	ginkgo.AfterEach(func() {
+		g.Expect(os.RemoveAll(dir)).To(gomega.Succeed())
+	})
+
+	deps := []*chart.Dependency{
+		{Name: "redis", Repository: "https://charts.example.com/stable", Version: "^1.2"},
+	}
+
+	ginkgo.It("leaves versions unchanged and writes a lock when none exists", func() {
+		resolver := NewResolver(dir, "")
+		lock, err := resolver.Resolve(deps, nil)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(lock.Dependencies).To(gomega.HaveLen(1))
+		g.Expect(lock.Dependencies[0].Version).To(gomega.Equal("^1.2"))
+
+		_, err = os.Stat(path.Join(dir, "Chart.lock"))
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+	})
+
+	ginkgo.It("uses the pinned version from an existing, matching Chart.lock", func() {
+		pinned := []*chart.Dependency{
+			{Name: "redis", Repository: "https://charts.example.com/stable", Version: "1.2.3"},
+		}
+		digest, err := hashDependencies(deps, pinned)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+
+		err = writeLockFile(path.Join(dir, "Chart.lock"), &chart.Lock{
+			Digest:       digest,
+			Dependencies: pinned,
+		})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+
+		lock, err := NewResolver(dir, "").Resolve(deps, nil)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(lock.Dependencies[0].Version).To(gomega.Equal("1.2.3"))
+	})
+
+	ginkgo.It("accepts a Chart.lock generated the way a real helm dependency update would", func() {
+		// A real Chart.lock is generated from the exact req slice at
+		// update time, so its recorded digest is hashDependencies(req,
+		// locked) for the locked versions it resolved to, not
+		// hashDependencies(req, req).
+		locked := []*chart.Dependency{
+			{Name: "redis", Repository: "https://charts.example.com/stable", Version: "1.2.3"},
+		}
+		digest, err := hashDependencies(deps, locked)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+
+		err = writeLockFile(path.Join(dir, "Chart.lock"), &chart.Lock{
+			Digest:       digest,
+			Dependencies: locked,
+		})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+
+		_, err = NewResolver(dir, "").Resolve(deps, nil)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+	})
+
+	ginkgo.It("fails when the lock digest does not match Chart.yaml", func() {
+		err := writeLockFile(path.Join(dir, "Chart.lock"), &chart.Lock{
+			Digest:       "sha256:stale",
+			Dependencies: deps,
+		})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+
+		_, err = NewResolver(dir, "").Resolve(deps, nil)
+		g.Expect(err).To(gomega.HaveOccurred())
+		g.Expect(err.Error()).To(gomega.ContainSubstring("does not match"))
+	})
+
+	ginkgo.It("caches resolution under cachepath when there is no chartpath", func() {
+		resolver := NewResolver("", dir)
+		lock, err := resolver.Resolve(deps, nil)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(lock.Dependencies[0].Version).To(gomega.Equal("^1.2"))
+
+		entries, err := os.ReadDir(dir)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(entries).ToNot(gomega.BeEmpty())
+	})
+})
+
+var _ = ginkgo.Describe("hashDependencies", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("changes when a dependency's version changes", func() {
+		a := []*chart.Dependency{{Name: "redis", Repository: "https://a.example.com", Version: "1.0.0"}}
+		b := []*chart.Dependency{{Name: "redis", Repository: "https://a.example.com", Version: "1.0.1"}}
+
+		digestA, err := hashDependencies(a, a)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		digestB, err := hashDependencies(b, b)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+
+		g.Expect(digestA).ToNot(gomega.Equal(digestB))
+	})
+
+	ginkgo.It("changes when the requested and locked dependencies differ", func() {
+		req := []*chart.Dependency{{Name: "redis", Repository: "https://a.example.com", Version: "^1.0.0"}}
+		locked := []*chart.Dependency{{Name: "redis", Repository: "https://a.example.com", Version: "1.0.1"}}
+
+		reqDigest, err := hashDependencies(req, req)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		lockedDigest, err := hashDependencies(req, locked)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+
+		g.Expect(reqDigest).ToNot(gomega.Equal(lockedDigest))
+	})
+})
+
+var _ = ginkgo.Describe("Resolver.VerifyDependencyArtifact", func() {
+	var g gomega.Gomega
+	var dir string
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+
+		var err error
+		dir, err = os.MkdirTemp("", "")
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+	})
+
+	ginkgo.AfterEach(func() {
+		g.Expect(os.RemoveAll(dir)).To(gomega.Succeed())
+	})
+
+	dependency := &chart.Dependency{Name: "redis", Repository: "https://charts.example.com/stable", Version: "^1.2"}
+
+	buildChart := func(version string) *chart.Chart {
+		return &chart.Chart{
+			Metadata: &chart.Metadata{Name: "redis", Version: version, APIVersion: chart.APIVersionV2},
+			Values:   map[string]interface{}{},
+		}
+	}
+
+	ginkgo.It("is a no-op without a cachepath", func() {
+		resolver := NewResolver(dir, "")
+		g.Expect(resolver.VerifyDependencyArtifact(dependency, "1.2.3", buildChart("1.2.3"))).To(gomega.Succeed())
+	})
+
+	ginkgo.It("records the digest on first use and accepts the same chart again", func() {
+		resolver := NewResolver("", dir)
+		g.Expect(resolver.VerifyDependencyArtifact(dependency, "1.2.3", buildChart("1.2.3"))).To(gomega.Succeed())
+		g.Expect(resolver.VerifyDependencyArtifact(dependency, "1.2.3", buildChart("1.2.3"))).To(gomega.Succeed())
+	})
+
+	ginkgo.It("fails when the same name/repository/version resolves to different content", func() {
+		resolver := NewResolver("", dir)
+		g.Expect(resolver.VerifyDependencyArtifact(dependency, "1.2.3", buildChart("1.2.3"))).To(gomega.Succeed())
+
+		tampered := buildChart("1.2.3")
+		tampered.Raw = []*chart.File{{Name: "values.yaml", Data: []byte("injected: true\n")}}
+
+		err := resolver.VerifyDependencyArtifact(dependency, "1.2.3", tampered)
+		g.Expect(err).To(gomega.HaveOccurred())
+		g.Expect(err.Error()).To(gomega.ContainSubstring("does not match"))
+	})
+})
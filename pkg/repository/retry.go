@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"oras.land/oras-go/v2/registry/remote/errcode"
+)
+
+// RetryConfig controls how transient failures from Helm/OCI repository
+// operations (registry logins, tag listing, chart/index downloads) are
+// retried. The zero value does not disable retries: a config with a
+// MaxAttempts of 0 is replaced by withRetry with defaultRetryConfig (5
+// attempts), not by a single attempt; set MaxAttempts to 1 explicitly to
+// disable retrying.
+type RetryConfig struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Jitter is the fraction (0 to 1) of each computed backoff that is
+	// randomized, to avoid many concurrent HelmReleases retrying in lockstep
+	// against the same registry.
+	Jitter float64
+}
+
+// defaultRetryConfig is used wherever a zero-value RetryConfig is seen,
+// i.e. when nothing more specific has been configured.
+func defaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxAttempts:    5,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Jitter:         0.2,
+	}
+}
+
+func (config RetryConfig) orDefault() RetryConfig {
+	if config.MaxAttempts <= 0 {
+		return defaultRetryConfig()
+	}
+	return config
+}
+
+func (config RetryConfig) backoffForAttempt(attempt int) time.Duration {
+	backoff := float64(config.InitialBackoff) * math.Pow(2, float64(attempt))
+	if max := float64(config.MaxBackoff); config.MaxBackoff > 0 && backoff > max {
+		backoff = max
+	}
+	if config.Jitter > 0 {
+		backoff *= 1 - config.Jitter + 2*config.Jitter*rand.Float64()
+	}
+	return time.Duration(backoff)
+}
+
+// retryAfterError reports a retryable HTTP response that carried a
+// Retry-After header, so withRetry can honor the server's requested delay
+// instead of its own exponential backoff.
+type retryAfterError struct {
+	statusCode int
+	retryAfter time.Duration
+}
+
+func (err *retryAfterError) Error() string {
+	return fmt.Sprintf(
+		"received status %d with Retry-After: %s",
+		err.statusCode,
+		err.retryAfter,
+	)
+}
+
+// retryAfterTransport wraps an http.RoundTripper, turning a retryable
+// response (see retryableStatusCode) that carries a Retry-After header
+// into a *retryAfterError instead of a normal response, so the header
+// survives up through client libraries that would otherwise discard it
+// while building their own error from the response body/status. A nil
+// base defaults to http.DefaultTransport.
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+func newRetryAfterTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &retryAfterTransport{base: base}
+}
+
+func (transport *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := transport.base.RoundTrip(req)
+	if err != nil || !retryableStatusCode(resp.StatusCode) {
+		return resp, err
+	}
+
+	retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"))
+	if !ok {
+		return resp, nil
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return nil, &retryAfterError{statusCode: resp.StatusCode, retryAfter: retryAfter}
+}
+
+// parseRetryAfter parses an HTTP Retry-After header value, which is either
+// a number of seconds or an HTTP-date (RFC 7231 section 7.1.3).
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(when); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// retryableStatusCode reports whether an HTTP status code observed from a
+// registry or Helm repository server warrants a retry: 429 and the 5xx
+// codes that typically indicate a transient server-side or gateway issue.
+func retryableStatusCode(statusCode int) bool {
+	switch statusCode {
+	case 429, 500, 502, 503, 504:
+		return true
+	default:
+		return false
+	}
+}
+
+// classifyError reports whether err, returned from an OCI registry or
+// Helm repository HTTP call, should be retried. It does not short-circuit
+// on 401/403/404 (the caller naturally won't retry those since they are
+// not matched by any of the retryable cases below).
+func classifyError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var retryAfterErr *retryAfterError
+	if errors.As(err, &retryAfterErr) {
+		return true
+	}
+
+	var errResponse *errcode.ErrorResponse
+	if errors.As(err, &errResponse) {
+		return retryableStatusCode(errResponse.StatusCode)
+	}
+
+	var errs errcode.Errors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			// Docker Hub rate limiting is reported as this nonstandard code
+			// rather than a 429 status, even via the OCI distribution API.
+			if e.Code == "TOOMANYREQUESTS" {
+				return true
+			}
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// helm.sh/helm/v3/pkg/getter's HTTPGetter reports non-200 responses as
+	// a plain "failed to fetch %s : %s" error wrapping resp.Status, with no
+	// structured status code to type-assert on.
+	message := err.Error()
+	for _, status := range []string{
+		"429 ", "500 ", "502 ", "503 ", "504 ", "TOOMANYREQUESTS",
+	} {
+		if strings.Contains(message, status) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetry runs operation, retrying it up to config.MaxAttempts times
+// (in total) with exponential backoff while classifyError considers its
+// error retryable, and logging each retry. When operation's error is a
+// *retryAfterError (see retryAfterTransport), the server's requested
+// delay is used in place of the computed exponential backoff. It gives
+// up immediately on a non-retryable error or when ctx is done.
+func withRetry(
+	ctx context.Context,
+	logger *slog.Logger,
+	config RetryConfig,
+	description string,
+	operation func() error,
+) error {
+	config = config.orDefault()
+
+	var err error
+	for attempt := 0; attempt < config.MaxAttempts; attempt++ {
+		if err = operation(); err == nil {
+			return nil
+		}
+		if !classifyError(err) || attempt == config.MaxAttempts-1 {
+			return err
+		}
+
+		backoff := config.backoffForAttempt(attempt)
+		var retryAfterErr *retryAfterError
+		if errors.As(err, &retryAfterErr) {
+			backoff = retryAfterErr.retryAfter
+		}
+		if logger != nil {
+			logger.
+				With("operation", description, "attempt", attempt+1, "backoff", backoff, "error", err).
+				Warn("Retrying after a transient error")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+	return err
+}
+
+// hostRateLimiter enforces a per-host token-bucket rate limit on Helm
+// repository/OCI registry operations, so that a manifest referencing many
+// charts from the same registry doesn't trip its rate limiting. A nil
+// *hostRateLimiter disables rate limiting entirely; NewHelmReleaseExpander
+// starts with an unlimited (rate.Inf) limiter instead of nil.
+type hostRateLimiter struct {
+	mu             sync.Mutex
+	limitersByHost map[string]*rate.Limiter
+	limit          rate.Limit
+	burst          int
+}
+
+// newHostRateLimiter returns a hostRateLimiter allowing up to limit
+// requests per second per host, with bursts of up to burst requests.
+func newHostRateLimiter(limit rate.Limit, burst int) *hostRateLimiter {
+	return &hostRateLimiter{
+		limitersByHost: map[string]*rate.Limiter{},
+		limit:          limit,
+		burst:          burst,
+	}
+}
+
+func (limiter *hostRateLimiter) limiterFor(host string) *rate.Limiter {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	hostLimiter, ok := limiter.limitersByHost[host]
+	if !ok {
+		hostLimiter = rate.NewLimiter(limiter.limit, limiter.burst)
+		limiter.limitersByHost[host] = hostLimiter
+	}
+	return hostLimiter
+}
+
+// wait blocks until an operation against host is allowed to proceed, or
+// ctx is done. It is a no-op on a nil *hostRateLimiter.
+func (limiter *hostRateLimiter) wait(ctx context.Context, host string) error {
+	if limiter == nil {
+		return nil
+	}
+	return limiter.limiterFor(host).Wait(ctx)
+}
@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+	"oras.land/oras-go/v2/registry/remote/errcode"
+)
+
+var _ = ginkgo.Describe("RetryConfig", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("falls back to the default when unset", func() {
+		g.Expect(RetryConfig{}.orDefault().MaxAttempts).To(gomega.Equal(defaultRetryConfig().MaxAttempts))
+	})
+
+	ginkgo.It("keeps an explicitly configured MaxAttempts", func() {
+		config := RetryConfig{MaxAttempts: 2}
+		g.Expect(config.orDefault().MaxAttempts).To(gomega.Equal(2))
+	})
+
+	ginkgo.It("caps backoff at MaxBackoff", func() {
+		config := RetryConfig{
+			InitialBackoff: time.Second,
+			MaxBackoff:     2 * time.Second,
+		}
+		g.Expect(config.backoffForAttempt(10)).To(gomega.Equal(2 * time.Second))
+	})
+})
+
+var _ = ginkgo.Describe("classifyError", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("does not retry a nil error", func() {
+		g.Expect(classifyError(nil)).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("retries a 503 errcode.ErrorResponse", func() {
+		err := &errcode.ErrorResponse{StatusCode: 503}
+		g.Expect(classifyError(err)).To(gomega.BeTrue())
+	})
+
+	ginkgo.It("does not retry a 404 errcode.ErrorResponse", func() {
+		err := &errcode.ErrorResponse{StatusCode: 404}
+		g.Expect(classifyError(err)).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("retries Docker Hub's nonstandard TOOMANYREQUESTS code", func() {
+		err := errcode.Errors{{Code: "TOOMANYREQUESTS"}}
+		g.Expect(classifyError(err)).To(gomega.BeTrue())
+	})
+
+	ginkgo.It("retries an unstructured error mentioning a 429 status", func() {
+		err := fmt.Errorf("failed to fetch https://example.com/chart.tgz : 429 Too Many Requests")
+		g.Expect(classifyError(err)).To(gomega.BeTrue())
+	})
+
+	ginkgo.It("does not retry an unrelated error", func() {
+		g.Expect(classifyError(errors.New("boom"))).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("retries a *retryAfterError", func() {
+		err := &retryAfterError{statusCode: 429, retryAfter: time.Second}
+		g.Expect(classifyError(err)).To(gomega.BeTrue())
+	})
+})
+
+var _ = ginkgo.Describe("parseRetryAfter", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("parses a delay-seconds value", func() {
+		delay, ok := parseRetryAfter("2")
+		g.Expect(ok).To(gomega.BeTrue())
+		g.Expect(delay).To(gomega.Equal(2 * time.Second))
+	})
+
+	ginkgo.It("parses an HTTP-date value in the future", func() {
+		when := time.Now().Add(time.Hour).UTC()
+		delay, ok := parseRetryAfter(when.Format(http.TimeFormat))
+		g.Expect(ok).To(gomega.BeTrue())
+		g.Expect(delay).To(gomega.BeNumerically("~", time.Hour, time.Minute))
+	})
+
+	ginkgo.It("rejects an empty value", func() {
+		_, ok := parseRetryAfter("")
+		g.Expect(ok).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("rejects an unparseable value", func() {
+		_, ok := parseRetryAfter("not a valid value")
+		g.Expect(ok).To(gomega.BeFalse())
+	})
+})
+
+var _ = ginkgo.Describe("retryAfterTransport", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("turns a 429 response carrying Retry-After into a *retryAfterError", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		client := &http.Client{Transport: newRetryAfterTransport(nil)}
+		_, err := client.Get(server.URL)
+
+		var retryAfterErr *retryAfterError
+		g.Expect(errors.As(err, &retryAfterErr)).To(gomega.BeTrue())
+		g.Expect(retryAfterErr.statusCode).To(gomega.Equal(http.StatusTooManyRequests))
+		g.Expect(retryAfterErr.retryAfter).To(gomega.Equal(7 * time.Second))
+	})
+
+	ginkgo.It("passes through a retryable response with no Retry-After header", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client := &http.Client{Transport: newRetryAfterTransport(nil)}
+		resp, err := client.Get(server.URL)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusServiceUnavailable))
+		_ = resp.Body.Close()
+	})
+
+	ginkgo.It("passes through a successful response unchanged", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, "ok")
+		}))
+		defer server.Close()
+
+		client := &http.Client{Transport: newRetryAfterTransport(nil)}
+		resp, err := client.Get(server.URL)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(resp.StatusCode).To(gomega.Equal(http.StatusOK))
+		body, err := io.ReadAll(resp.Body)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(string(body)).To(gomega.Equal("ok"))
+		_ = resp.Body.Close()
+	})
+})
+
+var _ = ginkgo.Describe("withRetry", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("gives up immediately on a non-retryable error", func() {
+		attempts := 0
+		err := withRetry(context.Background(), nil, RetryConfig{MaxAttempts: 3}, "test", func() error {
+			attempts++
+			return errors.New("permanent")
+		})
+		g.Expect(err).To(gomega.HaveOccurred())
+		g.Expect(attempts).To(gomega.Equal(1))
+	})
+
+	ginkgo.It("retries a transient error up to MaxAttempts", func() {
+		attempts := 0
+		err := withRetry(
+			context.Background(),
+			nil,
+			RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			"test",
+			func() error {
+				attempts++
+				return &errcode.ErrorResponse{StatusCode: 503}
+			},
+		)
+		g.Expect(err).To(gomega.HaveOccurred())
+		g.Expect(attempts).To(gomega.Equal(3))
+	})
+
+	ginkgo.It("succeeds once the operation stops failing", func() {
+		attempts := 0
+		err := withRetry(
+			context.Background(),
+			nil,
+			RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			"test",
+			func() error {
+				attempts++
+				if attempts < 2 {
+					return &errcode.ErrorResponse{StatusCode: 503}
+				}
+				return nil
+			},
+		)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(attempts).To(gomega.Equal(2))
+	})
+
+	ginkgo.It("waits for the Retry-After duration instead of its own backoff", func() {
+		attempts := 0
+		start := time.Now()
+		err := withRetry(
+			context.Background(),
+			nil,
+			// A large InitialBackoff would make the test slow if the
+			// Retry-After duration below were not honored instead.
+			RetryConfig{MaxAttempts: 2, InitialBackoff: time.Minute},
+			"test",
+			func() error {
+				attempts++
+				if attempts < 2 {
+					return &retryAfterError{statusCode: 429, retryAfter: 10 * time.Millisecond}
+				}
+				return nil
+			},
+		)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(attempts).To(gomega.Equal(2))
+		g.Expect(time.Since(start)).To(gomega.BeNumerically("<", time.Second))
+	})
+})
+
+var _ = ginkgo.Describe("hostRateLimiter", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("is a no-op on a nil receiver", func() {
+		var limiter *hostRateLimiter
+		g.Expect(limiter.wait(context.Background(), "example.com")).ToNot(gomega.HaveOccurred())
+	})
+
+	ginkgo.It("tracks a separate limiter per host", func() {
+		limiter := newHostRateLimiter(rate.Every(time.Hour), 1)
+		g.Expect(limiter.limiterFor("a.example.com")).ToNot(gomega.BeIdenticalTo(limiter.limiterFor("b.example.com")))
+		g.Expect(limiter.limiterFor("a.example.com")).To(gomega.BeIdenticalTo(limiter.limiterFor("a.example.com")))
+	})
+})
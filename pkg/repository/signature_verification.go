@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	cosignfulcio "github.com/sigstore/cosign/v2/cmd/cosign/cli/fulcio"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	cosignremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	cosignsig "github.com/sigstore/cosign/v2/pkg/signature"
+)
+
+// chartRegistryOptions carries the OCI registry access parameters (TLS
+// policy and credentials) a chart was pulled with, so signature
+// verification resolves the manifest digest and fetches signatures
+// against that same registry instead of cosign's defaults (anonymous,
+// HTTPS-only default keychain), which cannot reach a private or
+// plain-HTTP/insecure registry. The zero value matches cosign's own
+// defaults.
+type chartRegistryOptions struct {
+	insecure      bool
+	authenticator authn.Authenticator
+}
+
+// nameOptions returns the name.Option set used to parse and resolve
+// references against opts's registry, switching to plain HTTP when
+// opts.insecure is set.
+func (opts chartRegistryOptions) nameOptions() []name.Option {
+	if opts.insecure {
+		return []name.Option{name.Insecure}
+	}
+	return nil
+}
+
+// remoteOptions returns the cosignremote.Option set carrying opts's
+// authenticator, to be used everywhere cosign resolves a digest or
+// fetches a signature for the chart.
+func (opts chartRegistryOptions) remoteOptions() []cosignremote.Option {
+	if opts.authenticator == nil {
+		return nil
+	}
+	return []cosignremote.Option{
+		cosignremote.WithRemoteOptions(remote.WithAuth(opts.authenticator)),
+	}
+}
+
+// ChartVerificationProvider selects the signature scheme used to verify an
+// OCI Helm chart artifact, mirroring HelmChart.Spec.Verify.Provider in
+// fluxcd/source-controller. Only "cosign" is currently implemented.
+type ChartVerificationProvider string
+
+const (
+	ChartVerificationProviderCosign   ChartVerificationProvider = "cosign"
+	ChartVerificationProviderNotation ChartVerificationProvider = "notation"
+)
+
+// ChartVerificationConfig describes how an OCI chart artifact's cosign
+// signature should be checked before it is loaded. The zero value (empty
+// Provider) disables verification. When PublicKeys is non-empty, the chart
+// is accepted if any one of them verifies it; otherwise verification falls
+// back to keyless (Fulcio/Rekor) mode using CertIdentity/CertIdentityRegexp
+// and CertOIDCIssuer/CertOIDCIssuerRegexp to constrain the signing identity.
+type ChartVerificationConfig struct {
+	Provider             ChartVerificationProvider
+	PublicKeys           []string
+	CertIdentity         string
+	CertIdentityRegexp   string
+	CertOIDCIssuer       string
+	CertOIDCIssuerRegexp string
+}
+
+// Enabled reports whether config requests verification at all.
+func (config ChartVerificationConfig) Enabled() bool {
+	return config.Provider != ""
+}
+
+// chartSignatureVerifier caches cosign verification outcomes by manifest
+// digest, so that re-renders of the same chart within the same process
+// don't re-hit Rekor/Fulcio for every HelmRelease that references it.
+type chartSignatureVerifier struct {
+	mu      sync.Mutex
+	results map[string]error
+}
+
+func newChartSignatureVerifier() *chartSignatureVerifier {
+	return &chartSignatureVerifier{results: map[string]error{}}
+}
+
+// verify checks chartRef's signature against config, resolving the
+// manifest digest and fetching signatures using registryOpts so private
+// or insecure/plain-HTTP registries are reachable the same way the chart
+// itself was pulled. It returns a non-nil error naming the chart and the
+// identity it failed to match when it doesn't verify. It is a no-op when
+// !config.Enabled(). A nil *chartSignatureVerifier disables the
+// memoization of results by digest but still performs verification.
+func (verifier *chartSignatureVerifier) verify(
+	ctx context.Context,
+	chartRef string,
+	config ChartVerificationConfig,
+	registryOpts chartRegistryOptions,
+) error {
+	if !config.Enabled() {
+		return nil
+	}
+
+	ref, err := name.ParseReference(chartRef, registryOpts.nameOptions()...)
+	if err != nil {
+		return fmt.Errorf("unable to parse chart reference %s: %w", chartRef, err)
+	}
+
+	digest, err := cosignremote.ResolveDigest(ref, registryOpts.remoteOptions()...)
+	if err != nil {
+		return fmt.Errorf(
+			"unable to resolve manifest digest for chart %s: %w",
+			chartRef,
+			err,
+		)
+	}
+
+	if verifier != nil {
+		verifier.mu.Lock()
+		cached, ok := verifier.results[digest.String()]
+		verifier.mu.Unlock()
+		if ok {
+			return cached
+		}
+	}
+
+	verifyErr := verifyChartSignature(ctx, digest, config, registryOpts)
+
+	if verifier != nil {
+		verifier.mu.Lock()
+		verifier.results[digest.String()] = verifyErr
+		verifier.mu.Unlock()
+	}
+	if verifyErr != nil {
+		return fmt.Errorf(
+			"chart signature verification failed for %s (digest %s): %w",
+			chartRef,
+			digest.String(),
+			verifyErr,
+		)
+	}
+	return nil
+}
+
+// verifyChartSignature checks digest's cosign signature against config. It
+// tries each of config.PublicKeys in turn, succeeding if any one of them
+// verifies; with no public keys configured, it verifies keylessly against
+// Fulcio/Rekor, constrained by config's CertIdentity*/CertOIDCIssuer*
+// fields.
+func verifyChartSignature(
+	ctx context.Context,
+	digest name.Digest,
+	config ChartVerificationConfig,
+	registryOpts chartRegistryOptions,
+) error {
+	switch config.Provider {
+	case ChartVerificationProviderCosign:
+	case ChartVerificationProviderNotation:
+		return fmt.Errorf("notation chart verification is not implemented")
+	default:
+		return fmt.Errorf("unknown chart verification provider %q", config.Provider)
+	}
+
+	if len(config.PublicKeys) > 0 {
+		return verifyChartSignatureWithPublicKeys(ctx, digest, config.PublicKeys, registryOpts)
+	}
+	return verifyChartSignatureKeyless(ctx, digest, config, registryOpts)
+}
+
+func verifyChartSignatureWithPublicKeys(
+	ctx context.Context,
+	digest name.Digest,
+	publicKeys []string,
+	registryOpts chartRegistryOptions,
+) error {
+	var lastErr error
+	for _, publicKey := range publicKeys {
+		sigVerifier, err := cosignsig.LoadPublicKeyRaw([]byte(publicKey), crypto.SHA256)
+		if err != nil {
+			lastErr = fmt.Errorf("unable to parse configured public key: %w", err)
+			continue
+		}
+		_, _, err = cosign.VerifyImageSignatures(ctx, digest, &cosign.CheckOpts{
+			SigVerifier:        sigVerifier,
+			RegistryClientOpts: registryOpts.remoteOptions(),
+		})
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+func verifyChartSignatureKeyless(
+	ctx context.Context,
+	digest name.Digest,
+	config ChartVerificationConfig,
+	registryOpts chartRegistryOptions,
+) error {
+	roots, err := cosignfulcio.GetRoots()
+	if err != nil {
+		return fmt.Errorf("unable to load Fulcio root certificates: %w", err)
+	}
+	intermediates, err := cosignfulcio.GetIntermediates()
+	if err != nil {
+		return fmt.Errorf("unable to load Fulcio intermediate certificates: %w", err)
+	}
+	rekorPubKeys, err := cosign.GetRekorPubs(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load Rekor public keys: %w", err)
+	}
+
+	_, _, err = cosign.VerifyImageSignatures(ctx, digest, &cosign.CheckOpts{
+		RootCerts:          roots,
+		IntermediateCerts:  intermediates,
+		RekorPubKeys:       rekorPubKeys,
+		RegistryClientOpts: registryOpts.remoteOptions(),
+		Identities: []cosign.Identity{{
+			Subject:       config.CertIdentity,
+			SubjectRegExp: config.CertIdentityRegexp,
+			Issuer:        config.CertOIDCIssuer,
+			IssuerRegExp:  config.CertOIDCIssuerRegexp,
+		}},
+	})
+	return err
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+)
+
+func digestFixture(g gomega.Gomega) name.Digest {
+	digest, err := name.NewDigest(
+		"registry.example.com/charts/demo@sha256:" +
+			"0000000000000000000000000000000000000000000000000000000000000000",
+	)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	return digest
+}
+
+var _ = ginkgo.Describe("ChartVerificationConfig", func() {
+	ginkgo.It("is disabled when no provider is set", func() {
+		g := gomega.NewWithT(ginkgo.GinkgoT())
+
+		g.Expect(ChartVerificationConfig{}.Enabled()).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("is enabled once a provider is set", func() {
+		g := gomega.NewWithT(ginkgo.GinkgoT())
+
+		config := ChartVerificationConfig{Provider: ChartVerificationProviderCosign}
+		g.Expect(config.Enabled()).To(gomega.BeTrue())
+	})
+})
+
+var _ = ginkgo.Describe("chartSignatureVerifier", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("is a no-op when verification is disabled", func() {
+		verifier := newChartSignatureVerifier()
+
+		err := verifier.verify(
+			context.Background(), "not a valid reference", ChartVerificationConfig{}, chartRegistryOptions{},
+		)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+	})
+
+	ginkgo.It("rejects an unparseable chart reference", func() {
+		verifier := newChartSignatureVerifier()
+
+		err := verifier.verify(
+			context.Background(),
+			"not a valid reference",
+			ChartVerificationConfig{Provider: ChartVerificationProviderCosign},
+			chartRegistryOptions{},
+		)
+		g.Expect(err).To(gomega.HaveOccurred())
+	})
+
+	ginkgo.It("rejects an unknown verification provider", func() {
+		err := verifyChartSignature(context.Background(), digestFixture(g), ChartVerificationConfig{
+			Provider: "bogus",
+		}, chartRegistryOptions{})
+		g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("unknown chart verification provider")))
+	})
+
+	ginkgo.It("rejects the notation provider as not implemented", func() {
+		err := verifyChartSignature(context.Background(), digestFixture(g), ChartVerificationConfig{
+			Provider: ChartVerificationProviderNotation,
+		}, chartRegistryOptions{})
+		g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("not implemented")))
+	})
+
+	ginkgo.It("reaches a plain-HTTP local registry only once marked insecure", func() {
+		server := httptest.NewServer(registry.New())
+		defer server.Close()
+
+		image, err := random.Image(1024, 1)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+
+		chartRef := fmt.Sprintf("%s/charts/demo:1.0.0", server.Listener.Addr().String())
+		ref, err := name.ParseReference(chartRef, name.Insecure)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(remote.Write(ref, image)).To(gomega.Succeed())
+
+		config := ChartVerificationConfig{
+			Provider:   ChartVerificationProviderCosign,
+			PublicKeys: []string{"not a real public key"},
+		}
+
+		// Without registry options, cosign defaults to HTTPS and never
+		// reaches the plain-HTTP registry at all.
+		err = newChartSignatureVerifier().verify(context.Background(), chartRef, config, chartRegistryOptions{})
+		g.Expect(err).To(gomega.HaveOccurred())
+		g.Expect(err.Error()).To(gomega.ContainSubstring("unable to resolve manifest digest"))
+
+		// Marked insecure, it resolves the digest and only then fails, on
+		// the unparseable public key - proving the registry itself was
+		// reached.
+		err = newChartSignatureVerifier().verify(
+			context.Background(), chartRef, config, chartRegistryOptions{insecure: true},
+		)
+		g.Expect(err).To(gomega.HaveOccurred())
+		g.Expect(err.Error()).ToNot(gomega.ContainSubstring("unable to resolve manifest digest"))
+	})
+})
@@ -0,0 +1,287 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	helmv2beta2 "github.com/fluxcd/helm-controller/api/v2beta2"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/strvals"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+	k8syaml "sigs.k8s.io/yaml"
+
+	yamlutil "github.com/vladlosev/fouskoti/pkg/yaml"
+)
+
+// ValuesSource resolves a HelmRelease valuesFrom reference that is not
+// satisfied by a ConfigMap or Secret present in the same input stream
+// processed by ExpandHelmReleases, e.g. one read from a live cluster or a
+// directory of manifest files. The found return value is false (with a nil
+// error) when the referenced object does not exist, so that an Optional
+// valuesFrom reference can be skipped the same way whether the object is
+// absent from the input stream or from the source. A nil ValuesSource (the
+// default) means valuesFrom references are only resolved against the input
+// stream.
+type ValuesSource interface {
+	GetConfigMap(ctx context.Context, namespace, name string) (configMap *corev1.ConfigMap, found bool, err error)
+	GetSecret(ctx context.Context, namespace, name string) (secret *corev1.Secret, found bool, err error)
+}
+
+// valuesIndex indexes the ConfigMaps and Secrets present in an input
+// stream by namespace and name, so that resolveValuesFrom can resolve a
+// valuesFrom reference against them without rescanning manifestNodes for
+// every HelmRelease and every reference.
+type valuesIndex struct {
+	configMaps map[string]*yaml.RNode
+	secrets    map[string]*yaml.RNode
+}
+
+func valuesIndexKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// newValuesIndex scans nodes for core/v1 ConfigMap and Secret objects, the
+// only kinds a HelmRelease valuesFrom reference can name, and indexes them
+// by namespace/name.
+func newValuesIndex(nodes []*yaml.RNode) *valuesIndex {
+	index := &valuesIndex{
+		configMaps: map[string]*yaml.RNode{},
+		secrets:    map[string]*yaml.RNode{},
+	}
+	for _, node := range nodes {
+		if yamlutil.GetGroup(node) != "" {
+			continue
+		}
+		key := valuesIndexKey(node.GetNamespace(), node.GetName())
+		switch node.GetKind() {
+		case "ConfigMap":
+			index.configMaps[key] = node
+		case "Secret":
+			index.secrets[key] = node
+		}
+	}
+	return index
+}
+
+// lookupValuesReferenceData returns the raw string stored at ref's
+// valuesKey (defaulting to "values.yaml"), looking first among the
+// ConfigMaps/Secrets carried by the input stream and falling back to
+// source when the object isn't found there. found is false only when the
+// referenced ConfigMap/Secret itself doesn't exist; a missing valuesKey in
+// an object that does exist is always an error, matching Flux's documented
+// behavior that optional only suppresses a not-found error for the
+// referenced object, not for its valuesKey.
+func lookupValuesReferenceData(
+	ctx context.Context,
+	ref helmv2beta2.ValuesReference,
+	namespace string,
+	index *valuesIndex,
+	source ValuesSource,
+) (data string, found bool, err error) {
+	key := valuesIndexKey(namespace, ref.Name)
+
+	switch ref.Kind {
+	case "ConfigMap":
+		var configMap corev1.ConfigMap
+		if node, ok := index.configMaps[key]; ok {
+			if err := decodeToObject(node, &configMap); err != nil {
+				return "", false, fmt.Errorf(
+					"unable to decode ConfigMap %s/%s: %w",
+					namespace,
+					ref.Name,
+					err,
+				)
+			}
+		} else {
+			if source == nil {
+				return "", false, nil
+			}
+			resolved, ok, err := source.GetConfigMap(ctx, namespace, ref.Name)
+			if err != nil {
+				return "", false, fmt.Errorf(
+					"unable to get ConfigMap %s/%s from values source: %w",
+					namespace,
+					ref.Name,
+					err,
+				)
+			}
+			if !ok {
+				return "", false, nil
+			}
+			configMap = *resolved
+		}
+		data, ok := configMap.Data[ref.GetValuesKey()]
+		if !ok {
+			return "", false, fmt.Errorf(
+				"ConfigMap %s/%s has no key %s",
+				namespace,
+				ref.Name,
+				ref.GetValuesKey(),
+			)
+		}
+		return data, true, nil
+	case "Secret":
+		var secret corev1.Secret
+		if node, ok := index.secrets[key]; ok {
+			if err := decodeToObject(node, &secret); err != nil {
+				return "", false, fmt.Errorf(
+					"unable to decode Secret %s/%s: %w",
+					namespace,
+					ref.Name,
+					err,
+				)
+			}
+		} else {
+			if source == nil {
+				return "", false, nil
+			}
+			resolved, ok, err := source.GetSecret(ctx, namespace, ref.Name)
+			if err != nil {
+				return "", false, fmt.Errorf(
+					"unable to get Secret %s/%s from values source: %w",
+					namespace,
+					ref.Name,
+					err,
+				)
+			}
+			if !ok {
+				return "", false, nil
+			}
+			secret = *resolved
+		}
+		if raw, ok := secret.Data[ref.GetValuesKey()]; ok {
+			return string(raw), true, nil
+		}
+		if raw, ok := secret.StringData[ref.GetValuesKey()]; ok {
+			return raw, true, nil
+		}
+		return "", false, fmt.Errorf(
+			"Secret %s/%s has no key %s",
+			namespace,
+			ref.Name,
+			ref.GetValuesKey(),
+		)
+	default:
+		return "", false, fmt.Errorf("unsupported valuesFrom kind %s", ref.Kind)
+	}
+}
+
+// resolveValuesReference resolves a non-targetPath valuesFrom reference to
+// the table of values its valuesKey holds.
+func resolveValuesReference(
+	ctx context.Context,
+	ref helmv2beta2.ValuesReference,
+	namespace string,
+	index *valuesIndex,
+	source ValuesSource,
+) (map[string]interface{}, error) {
+	raw, found, err := lookupValuesReferenceData(ctx, ref, namespace, index, source)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		if ref.Optional {
+			return map[string]interface{}{}, nil
+		}
+		return nil, fmt.Errorf("%s %s/%s not found", ref.Kind, namespace, ref.Name)
+	}
+
+	values := map[string]interface{}{}
+	if err := k8syaml.Unmarshal([]byte(raw), &values); err != nil {
+		return nil, fmt.Errorf(
+			"unable to parse values from %s %s/%s key %s: %w",
+			ref.Kind,
+			namespace,
+			ref.Name,
+			ref.GetValuesKey(),
+			err,
+		)
+	}
+	return values, nil
+}
+
+// applyValuesReferenceTargetPath resolves a targetPath valuesFrom
+// reference and writes it into dest the way "helm --set" would, per
+// Flux's documented targetPath formatting.
+func applyValuesReferenceTargetPath(
+	ctx context.Context,
+	ref helmv2beta2.ValuesReference,
+	namespace string,
+	index *valuesIndex,
+	source ValuesSource,
+	dest map[string]interface{},
+) error {
+	raw, found, err := lookupValuesReferenceData(ctx, ref, namespace, index, source)
+	if err != nil {
+		return err
+	}
+	if !found {
+		if ref.Optional {
+			return nil
+		}
+		return fmt.Errorf("%s %s/%s not found", ref.Kind, namespace, ref.Name)
+	}
+
+	if err := strvals.ParseInto(fmt.Sprintf("%s=%s", ref.TargetPath, raw), dest); err != nil {
+		return fmt.Errorf(
+			"unable to set targetPath %s from %s %s/%s: %w",
+			ref.TargetPath,
+			ref.Kind,
+			namespace,
+			ref.Name,
+			err,
+		)
+	}
+	return nil
+}
+
+// resolveValuesFrom composes release's spec.valuesFrom and spec.values the
+// way helm-controller does: valuesFrom entries without a targetPath are
+// merged as whole tables in list order (later entries overriding earlier),
+// inline values are then merged on top of those, and finally every
+// targetPath entry is applied as an individual "--set"-style write, in
+// their original relative order, so that targetPath always wins last
+// regardless of where it appears in the list. See
+// https://fluxcd.io/flux/components/helm/helmreleases/#values-references.
+func resolveValuesFrom(
+	ctx context.Context,
+	release *helmv2beta2.HelmRelease,
+	index *valuesIndex,
+	source ValuesSource,
+) (map[string]interface{}, error) {
+	merged := map[string]interface{}{}
+	var targetPathRefs []helmv2beta2.ValuesReference
+
+	for _, ref := range release.Spec.ValuesFrom {
+		if ref.TargetPath != "" {
+			targetPathRefs = append(targetPathRefs, ref)
+			continue
+		}
+		values, err := resolveValuesReference(ctx, ref, release.Namespace, index, source)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"unable to resolve valuesFrom entry for HelmRelease %s/%s: %w",
+				release.Namespace,
+				release.Name,
+				err,
+			)
+		}
+		merged = chartutil.MergeTables(values, merged)
+	}
+
+	merged = chartutil.MergeTables(release.GetValues(), merged)
+
+	for _, ref := range targetPathRefs {
+		if err := applyValuesReferenceTargetPath(ctx, ref, release.Namespace, index, source, merged); err != nil {
+			return nil, fmt.Errorf(
+				"unable to resolve valuesFrom entry for HelmRelease %s/%s: %w",
+				release.Namespace,
+				release.Name,
+				err,
+			)
+		}
+	}
+
+	return merged, nil
+}
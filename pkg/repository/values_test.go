@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	helmv2beta2 "github.com/fluxcd/helm-controller/api/v2beta2"
+)
+
+func mustParseValuesNodes(g gomega.Gomega, input string) []*yaml.RNode {
+	reader := kio.ByteReader{Reader: bytes.NewBufferString(input)}
+	nodes, err := reader.Read()
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	return nodes
+}
+
+type fakeValuesSource struct {
+	configMaps map[string]*corev1.ConfigMap
+	secrets    map[string]*corev1.Secret
+}
+
+func (source *fakeValuesSource) GetConfigMap(
+	_ context.Context,
+	namespace, name string,
+) (*corev1.ConfigMap, bool, error) {
+	configMap, ok := source.configMaps[valuesIndexKey(namespace, name)]
+	return configMap, ok, nil
+}
+
+func (source *fakeValuesSource) GetSecret(
+	_ context.Context,
+	namespace, name string,
+) (*corev1.Secret, bool, error) {
+	secret, ok := source.secrets[valuesIndexKey(namespace, name)]
+	return secret, ok, nil
+}
+
+var _ = ginkgo.Describe("newValuesIndex", func() {
+	var g gomega.Gomega
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+	})
+
+	ginkgo.It("indexes ConfigMaps and Secrets by namespace/name, ignoring other kinds", func() {
+		nodes := mustParseValuesNodes(g, strings.Join([]string{
+			"apiVersion: v1",
+			"kind: ConfigMap",
+			"metadata:",
+			"  namespace: testns",
+			"  name: values",
+			"data:",
+			"  values.yaml: 'foo: bar'",
+			"---",
+			"apiVersion: v1",
+			"kind: Secret",
+			"metadata:",
+			"  namespace: testns",
+			"  name: secret-values",
+			"data: {}",
+			"---",
+			"apiVersion: source.toolkit.fluxcd.io/v1beta2",
+			"kind: GitRepository",
+			"metadata:",
+			"  namespace: testns",
+			"  name: local",
+			"spec:",
+			"  url: https://example.com/repo.git",
+		}, "\n"))
+
+		index := newValuesIndex(nodes)
+		g.Expect(index.configMaps).To(gomega.HaveKey("testns/values"))
+		g.Expect(index.secrets).To(gomega.HaveKey("testns/secret-values"))
+		g.Expect(index.configMaps).To(gomega.HaveLen(1))
+		g.Expect(index.secrets).To(gomega.HaveLen(1))
+	})
+})
+
+var _ = ginkgo.Describe("resolveValuesFrom", func() {
+	var g gomega.Gomega
+	var ctx context.Context
+
+	ginkgo.BeforeEach(func() {
+		g = gomega.NewWithT(ginkgo.GinkgoT())
+		ctx = context.Background()
+	})
+
+	newRelease := func(refs []helmv2beta2.ValuesReference, inlineValues string) *helmv2beta2.HelmRelease {
+		release := &helmv2beta2.HelmRelease{}
+		release.Namespace = "testns"
+		release.Name = "test"
+		release.Spec.ValuesFrom = refs
+		if inlineValues != "" {
+			release.Spec.Values = &apiextensionsv1.JSON{Raw: []byte(inlineValues)}
+		}
+		return release
+	}
+
+	ginkgo.It("merges valuesFrom entries in order, later overriding earlier", func() {
+		nodes := mustParseValuesNodes(g, strings.Join([]string{
+			"apiVersion: v1",
+			"kind: ConfigMap",
+			"metadata:",
+			"  namespace: testns",
+			"  name: first",
+			"data:",
+			"  values.yaml: |",
+			"    foo: a",
+			"    bar: a",
+			"---",
+			"apiVersion: v1",
+			"kind: ConfigMap",
+			"metadata:",
+			"  namespace: testns",
+			"  name: second",
+			"data:",
+			"  values.yaml: |",
+			"    foo: b",
+		}, "\n"))
+		index := newValuesIndex(nodes)
+
+		release := newRelease([]helmv2beta2.ValuesReference{
+			{Kind: "ConfigMap", Name: "first"},
+			{Kind: "ConfigMap", Name: "second"},
+		}, "")
+
+		values, err := resolveValuesFrom(ctx, release, index, nil)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(values).To(gomega.Equal(map[string]interface{}{"foo": "b", "bar": "a"}))
+	})
+
+	ginkgo.It("overrides valuesFrom entries with inline values", func() {
+		nodes := mustParseValuesNodes(g, strings.Join([]string{
+			"apiVersion: v1",
+			"kind: ConfigMap",
+			"metadata:",
+			"  namespace: testns",
+			"  name: first",
+			"data:",
+			"  values.yaml: |",
+			"    foo: a",
+		}, "\n"))
+		index := newValuesIndex(nodes)
+
+		release := newRelease([]helmv2beta2.ValuesReference{
+			{Kind: "ConfigMap", Name: "first"},
+		}, `{"foo":"inline"}`)
+
+		values, err := resolveValuesFrom(ctx, release, index, nil)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(values).To(gomega.Equal(map[string]interface{}{"foo": "inline"}))
+	})
+
+	ginkgo.It("applies targetPath entries last, overriding inline values", func() {
+		nodes := mustParseValuesNodes(g, strings.Join([]string{
+			"apiVersion: v1",
+			"kind: Secret",
+			"metadata:",
+			"  namespace: testns",
+			"  name: tls",
+			"stringData:",
+			"  crt: cert-data",
+		}, "\n"))
+		index := newValuesIndex(nodes)
+
+		release := newRelease([]helmv2beta2.ValuesReference{
+			{Kind: "Secret", Name: "tls", ValuesKey: "crt", TargetPath: "tls.crt"},
+		}, `{"tls":{"crt":"inline-cert"}}`)
+
+		values, err := resolveValuesFrom(ctx, release, index, nil)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(values).To(gomega.Equal(map[string]interface{}{
+			"tls": map[string]interface{}{"crt": "cert-data"},
+		}))
+	})
+
+	ginkgo.It("skips an optional reference whose object is missing", func() {
+		release := newRelease([]helmv2beta2.ValuesReference{
+			{Kind: "ConfigMap", Name: "missing", Optional: true},
+		}, `{"foo":"bar"}`)
+
+		values, err := resolveValuesFrom(ctx, release, newValuesIndex(nil), nil)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(values).To(gomega.Equal(map[string]interface{}{"foo": "bar"}))
+	})
+
+	ginkgo.It("fails for a required reference whose object is missing", func() {
+		release := newRelease([]helmv2beta2.ValuesReference{
+			{Kind: "ConfigMap", Name: "missing"},
+		}, "")
+
+		_, err := resolveValuesFrom(ctx, release, newValuesIndex(nil), nil)
+		g.Expect(err).To(gomega.HaveOccurred())
+		g.Expect(err.Error()).To(gomega.ContainSubstring("not found"))
+	})
+
+	ginkgo.It("fails when an existing object lacks the requested key, even if optional", func() {
+		nodes := mustParseValuesNodes(g, strings.Join([]string{
+			"apiVersion: v1",
+			"kind: ConfigMap",
+			"metadata:",
+			"  namespace: testns",
+			"  name: first",
+			"data:",
+			"  other-key.yaml: |",
+			"    foo: a",
+		}, "\n"))
+
+		release := newRelease([]helmv2beta2.ValuesReference{
+			{Kind: "ConfigMap", Name: "first", Optional: true},
+		}, "")
+
+		_, err := resolveValuesFrom(ctx, release, newValuesIndex(nodes), nil)
+		g.Expect(err).To(gomega.HaveOccurred())
+		g.Expect(err.Error()).To(gomega.ContainSubstring("no key"))
+	})
+
+	ginkgo.It("falls back to the ValuesSource when the object isn't in the input stream", func() {
+		source := &fakeValuesSource{
+			configMaps: map[string]*corev1.ConfigMap{
+				"testns/from-cluster": {
+					Data: map[string]string{"values.yaml": "foo: from-source"},
+				},
+			},
+		}
+		release := newRelease([]helmv2beta2.ValuesReference{
+			{Kind: "ConfigMap", Name: "from-cluster"},
+		}, "")
+
+		values, err := resolveValuesFrom(ctx, release, newValuesIndex(nil), source)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(values).To(gomega.Equal(map[string]interface{}{"foo": "from-source"}))
+	})
+
+	ginkgo.It("wraps an error returned by the ValuesSource", func() {
+		source := &erroringValuesSource{err: errors.New("boom")}
+		release := newRelease([]helmv2beta2.ValuesReference{
+			{Kind: "ConfigMap", Name: "anything"},
+		}, "")
+
+		_, err := resolveValuesFrom(ctx, release, newValuesIndex(nil), source)
+		g.Expect(err).To(gomega.HaveOccurred())
+		g.Expect(err.Error()).To(gomega.ContainSubstring("boom"))
+	})
+})
+
+type erroringValuesSource struct {
+	err error
+}
+
+func (source *erroringValuesSource) GetConfigMap(
+	context.Context,
+	string,
+	string,
+) (*corev1.ConfigMap, bool, error) {
+	return nil, false, source.err
+}
+
+func (source *erroringValuesSource) GetSecret(
+	context.Context,
+	string,
+	string,
+) (*corev1.Secret, bool, error) {
+	return nil, false, source.err
+}